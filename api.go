@@ -0,0 +1,593 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	API_PREFIX = "/api/v1/"
+
+	SSE_KEEPALIVE_INTERVAL = 15 * time.Second
+
+	// POST /api/v1/reload waits for the reload
+	API_RELOAD_TIMEOUT = time.Minute
+
+	JOB_STATE_IDLE      = "idle"
+	JOB_STATE_RUNNING   = "running"
+	JOB_STATE_SUCCEEDED = "succeeded"
+	JOB_STATE_FAILED    = "failed"
+)
+
+var (
+	daemonStatus = &DaemonStatus{Started: time.Now(), Files: []string{}}
+)
+
+// Status of daemon (uptime, loaded crontabs and last reload)
+type DaemonStatus struct {
+	mu              sync.RWMutex
+	Started         time.Time
+	Files           []string
+	LastReload      time.Time
+	LastReloadError error
+	ReloadCount     uint64
+}
+
+// Record result of (re)loading crontabs
+func (s *DaemonStatus) Reloaded(crontabEntries []CrontabEntry, err error) {
+	files := map[string]bool{}
+	for _, entry := range crontabEntries {
+		if entry.Source != "" {
+			files[entry.Source] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Files = []string{}
+	for file := range files {
+		s.Files = append(s.Files, file)
+	}
+	sort.Strings(s.Files)
+
+	s.LastReload = time.Now()
+	s.LastReloadError = err
+	s.ReloadCount++
+}
+
+// GET /api/v1/events streams Event (see events.go)
+
+// GET /api/v1/info
+type ApiInfo struct {
+	Name       string          `json:"name"`
+	Version    string          `json:"version"`
+	Started    time.Time       `json:"started"`
+	Uptime     float64         `json:"uptime"` // seconds
+	Files      []string        `json:"files"`  // loaded crontabs and run-parts executables
+	LastReload ApiReloadStatus `json:"last_reload"`
+}
+
+type ApiReloadStatus struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	Count   uint64    `json:"count"` // number of loads (initial load and reloads)
+}
+
+// GET /api/v1/jobs, GET /api/v1/jobs/<id> (id or name of job)
+type ApiJob struct {
+	Id       int        `json:"id"`
+	Name     string     `json:"name"`
+	Spec     string     `json:"spec"`
+	User     string     `json:"user"`
+	Command  string     `json:"command"`
+	Source   string     `json:"source"`
+	Tags     []string   `json:"tags"`
+	State    string     `json:"state"` // idle (not run yet), running, succeeded or failed
+	Paused   bool       `json:"paused"`
+	Next     *time.Time `json:"next,omitempty"`
+	Prev     *time.Time `json:"prev,omitempty"`
+	LastRun  *ApiRun    `json:"last_run,omitempty"`
+	ProcAttr string     `json:"proc_attr,omitempty"`
+	Env      []string   `json:"env"` // secrets are masked
+}
+
+// GET /api/v1/runs, GET /api/v1/jobs/<id>/runs, GET /api/v1/runs/<id> (with output)
+//
+// GET /api/v1/runs/<id>/stream streams StreamEvent (started, output, finished) as server-sent events
+type ApiRun struct {
+	Id        uint64     `json:"id"`
+	JobId     int        `json:"job_id"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end,omitempty"` // not set while running
+	Running   bool       `json:"running"`
+	ExitCode  int        `json:"exit_code"`
+	Error     string     `json:"error,omitempty"`
+	TimedOut  bool       `json:"timed_out"`
+	OOMKilled bool       `json:"oom_killed"`
+	Duration  float64    `json:"duration"` // seconds
+	Output    *string    `json:"output,omitempty"`
+}
+
+// POST /api/v1/jobs/<id>/run
+type ApiRunStarted struct {
+	RunId uint64 `json:"run_id"`
+	JobId int    `json:"job_id"`
+}
+
+// POST /api/v1/reload returns ReloadResult (see reload.go)
+
+// Error response
+type ApiError struct {
+	Error string `json:"error"`
+}
+
+// Handler of json api (/api/v1/)
+type ApiHandler struct {
+	runner *Runner
+}
+
+func NewApiHandler(runner *Runner) *ApiHandler {
+	return &ApiHandler{runner: runner}
+}
+
+func (h *ApiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, API_PREFIX), "/"), "/")
+
+	if r.Method == http.MethodPost {
+		h.servePost(w, r, path)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		apiWriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "info":
+		h.info(w)
+	case len(path) == 1 && path[0] == "events":
+		h.events(w, r)
+	case len(path) == 1 && path[0] == "jobs":
+		h.jobs(w)
+	case len(path) == 2 && path[0] == "jobs":
+		h.job(w, path[1])
+	case len(path) == 3 && path[0] == "jobs" && path[2] == "runs":
+		h.jobRuns(w, path[1])
+	case len(path) == 1 && path[0] == "runs":
+		h.runs(w, -1)
+	case len(path) == 2 && path[0] == "runs":
+		h.run(w, path[1])
+	case len(path) == 3 && path[0] == "runs" && path[2] == "stream":
+		h.runStream(w, r, path[1])
+	default:
+		apiWriteError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// Mutating api calls (require write scope if authentication is enabled)
+func (h *ApiHandler) servePost(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 3 && path[0] == "jobs" && path[2] == "run":
+		h.runJob(w, path[1])
+	case len(path) == 3 && path[0] == "jobs" && path[2] == "pause":
+		h.pauseJob(w, path[1], true)
+	case len(path) == 3 && path[0] == "jobs" && path[2] == "resume":
+		h.pauseJob(w, path[1], false)
+	case len(path) == 1 && path[0] == "reload":
+		h.reload(w, r)
+	default:
+		apiWriteError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// POST /api/v1/jobs/<id>/run (returns ApiRunStarted, run can be followed with /api/v1/runs/<id>/stream)
+func (h *ApiHandler) runJob(w http.ResponseWriter, id string) {
+	job, ok := h.lookupJob(w, id)
+	if !ok {
+		return
+	}
+
+	runId, err := h.runner.RunNow(job.Id)
+	if err != nil {
+		apiWriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	apiWriteJson(w, http.StatusAccepted, ApiRunStarted{RunId: runId, JobId: job.Id})
+}
+
+// POST /api/v1/jobs/<id>/pause, POST /api/v1/jobs/<id>/resume (returns ApiJob)
+func (h *ApiHandler) pauseJob(w http.ResponseWriter, id string, paused bool) {
+	job, ok := h.lookupJob(w, id)
+	if !ok {
+		return
+	}
+
+	if err := h.runner.SetPaused(job.Id, paused); err != nil {
+		apiWriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	job, _ = h.runner.GetJob(job.Id)
+	apiWriteJson(w, http.StatusOK, h.apiJob(job))
+}
+
+// POST /api/v1/reload (returns ReloadResult with diff and errors of cronjobs)
+func (h *ApiHandler) reload(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), API_RELOAD_TIMEOUT)
+	defer cancel()
+
+	result, err := reloadAndWait(ctx, "api")
+	if err != nil {
+		apiWriteError(w, http.StatusGatewayTimeout, fmt.Sprintf("reload not finished: %v", err))
+		return
+	}
+
+	apiWriteJson(w, http.StatusOK, result)
+}
+
+func (h *ApiHandler) info(w http.ResponseWriter) {
+	daemonStatus.mu.RLock()
+	defer daemonStatus.mu.RUnlock()
+
+	info := ApiInfo{
+		Name:    Name,
+		Version: Version,
+		Started: daemonStatus.Started,
+		Uptime:  time.Since(daemonStatus.Started).Seconds(),
+		Files:   daemonStatus.Files,
+		LastReload: ApiReloadStatus{
+			Time:    daemonStatus.LastReload,
+			Success: daemonStatus.LastReloadError == nil,
+			Count:   daemonStatus.ReloadCount,
+		},
+	}
+	if daemonStatus.LastReloadError != nil {
+		info.LastReload.Error = daemonStatus.LastReloadError.Error()
+	}
+
+	apiWriteJson(w, http.StatusOK, info)
+}
+
+func (h *ApiHandler) jobs(w http.ResponseWriter) {
+	ret := []ApiJob{}
+	for _, job := range h.runner.GetJobs() {
+		ret = append(ret, h.apiJob(job))
+	}
+
+	apiWriteJson(w, http.StatusOK, ret)
+}
+
+func (h *ApiHandler) job(w http.ResponseWriter, id string) {
+	job, ok := h.lookupJob(w, id)
+	if !ok {
+		return
+	}
+
+	apiWriteJson(w, http.StatusOK, h.apiJob(job))
+}
+
+func (h *ApiHandler) jobRuns(w http.ResponseWriter, id string) {
+	job, ok := h.lookupJob(w, id)
+	if !ok {
+		return
+	}
+
+	h.runs(w, job.Id)
+}
+
+func (h *ApiHandler) runs(w http.ResponseWriter, jobId int) {
+	ret := []ApiRun{}
+	for _, run := range h.runner.History().List(jobId) {
+		ret = append(ret, apiRun(run, false))
+	}
+
+	apiWriteJson(w, http.StatusOK, ret)
+}
+
+func (h *ApiHandler) run(w http.ResponseWriter, id string) {
+	runId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		apiWriteError(w, http.StatusBadRequest, "invalid run id")
+		return
+	}
+
+	run, ok := h.runner.History().Get(runId)
+	if !ok {
+		apiWriteError(w, http.StatusNotFound, "run not found")
+		return
+	}
+
+	apiWriteJson(w, http.StatusOK, apiRun(run, true))
+}
+
+// Stream output and lifecycle events of run as server-sent events
+//
+// Running runs are streamed live, finished runs are replayed from the run history.
+func (h *ApiHandler) runStream(w http.ResponseWriter, r *http.Request, id string) {
+	runId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		apiWriteError(w, http.StatusBadRequest, "invalid run id")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiWriteError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	var backlog []StreamEvent
+	var events chan StreamEvent
+
+	if stream, ok := h.runner.Stream(runId); ok {
+		backlog, events = stream.Subscribe()
+		defer stream.Unsubscribe(events)
+	} else if run, ok := h.runner.History().Get(runId); ok && !run.Running {
+		backlog = runStreamEvents(run)
+	} else {
+		apiWriteError(w, http.StatusNotFound, "run not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range backlog {
+		sseWriteEvent(w, event.Type, event)
+	}
+	flusher.Flush()
+
+	if events == nil {
+		return
+	}
+
+	keepalive := time.NewTicker(SSE_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			sseWriteEvent(w, event.Type, event)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Stream daemon and cronjob events as server-sent events or ndjson (?format=ndjson)
+//
+// Clients can resume with the id of the last received event (Last-Event-ID header
+// or ?cursor=<id>), X-Events-Missed is set if events were already dropped from the buffer.
+func (h *ApiHandler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiWriteError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	cursorValue := r.URL.Query().Get("cursor")
+	if cursorValue == "" {
+		cursorValue = r.Header.Get("Last-Event-ID")
+	}
+
+	var cursor uint64
+	resume := cursorValue != ""
+	if resume {
+		var err error
+		if cursor, err = strconv.ParseUint(cursorValue, 10, 64); err != nil {
+			apiWriteError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	}
+
+	ndjson := r.URL.Query().Get("format") == "ndjson" ||
+		strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") && !strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+
+	backlog, events, missed := eventLog.Subscribe(cursor, resume)
+	defer eventLog.Unsubscribe(events)
+
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if missed > 0 {
+		w.Header().Set("X-Events-Missed", strconv.FormatUint(missed, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event Event) {
+		if ndjson {
+			json.NewEncoder(w).Encode(event)
+		} else {
+			fmt.Fprintf(w, "id: %d\n", event.Id)
+			sseWriteEvent(w, event.Type, event)
+		}
+	}
+
+	for _, event := range backlog {
+		write(event)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(SSE_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// too slow, client has to resume with cursor
+				return
+			}
+			write(event)
+			flusher.Flush()
+		case <-keepalive.C:
+			if ndjson {
+				fmt.Fprint(w, "\n")
+			} else {
+				fmt.Fprint(w, ": keepalive\n\n")
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Write server-sent event with json data
+func sseWriteEvent(w http.ResponseWriter, event string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// Return job by id or name, writes error response if job doesn't exist
+func (h *ApiHandler) lookupJob(w http.ResponseWriter, id string) (Job, bool) {
+	if jobId, err := strconv.Atoi(id); err == nil {
+		job, ok := h.runner.GetJob(jobId)
+		if !ok {
+			apiWriteError(w, http.StatusNotFound, "job not found")
+			return Job{}, false
+		}
+		return job, true
+	}
+
+	var found []Job
+	for _, job := range h.runner.GetJobs() {
+		if job.Name == id {
+			found = append(found, job)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		apiWriteError(w, http.StatusNotFound, "job not found")
+		return Job{}, false
+	case 1:
+		return found[0], true
+	default:
+		apiWriteError(w, http.StatusConflict, fmt.Sprintf("job name %q is ambiguous, use job id", id))
+		return Job{}, false
+	}
+}
+
+func (h *ApiHandler) apiJob(job Job) ApiJob {
+	ret := ApiJob{
+		Id:      job.Id,
+		Name:    job.Name,
+		Spec:    job.Spec,
+		User:    job.User,
+		Command: job.Command,
+		Source:  job.Source,
+		Tags:    job.Tags,
+		State:   jobState(job),
+		Paused:  job.Paused,
+		Next:    apiTime(job.Next),
+		Prev:    apiTime(job.Prev),
+		Env:     job.Env,
+	}
+
+	if !job.ProcAttr.IsEmpty() {
+		ret.ProcAttr = job.ProcAttr.String()
+	}
+
+	if runs := h.runner.History().List(job.Id); len(runs) > 0 {
+		lastRun := apiRun(runs[0], false)
+		ret.LastRun = &lastRun
+	}
+
+	if ret.Env == nil {
+		ret.Env = []string{}
+	}
+
+	if ret.Tags == nil {
+		ret.Tags = []string{}
+	}
+
+	return ret
+}
+
+// Return state of job (idle, running, succeeded or failed)
+func jobState(job Job) string {
+	switch {
+	case job.Running > 0:
+		return JOB_STATE_RUNNING
+	case !job.Updated:
+		return JOB_STATE_IDLE
+	case job.Status != nil:
+		return JOB_STATE_FAILED
+	default:
+		return JOB_STATE_SUCCEEDED
+	}
+}
+
+func apiRun(run Run, withOutput bool) ApiRun {
+	ret := ApiRun{
+		Id:        run.Id,
+		JobId:     run.JobId,
+		Start:     run.Start,
+		Running:   run.Running,
+		ExitCode:  run.ExitCode,
+		Error:     run.Error,
+		TimedOut:  run.TimedOut,
+		OOMKilled: run.OOMKilled,
+		Duration:  run.Elapsed.Seconds(),
+	}
+
+	if !run.Running {
+		ret.End = apiTime(run.End)
+	} else {
+		ret.Duration = time.Since(run.Start).Seconds()
+	}
+
+	if withOutput {
+		output := run.Output
+		ret.Output = &output
+	}
+
+	return ret
+}
+
+// Return pointer of time (nil for zero time, omitted in json)
+func apiTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func apiWriteJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func apiWriteError(w http.ResponseWriter, status int, message string) {
+	apiWriteJson(w, status, ApiError{Error: message})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// metrics, web interface and read-only api calls
+	AUTH_SCOPE_READ = "read"
+
+	// mutating api calls (includes read)
+	AUTH_SCOPE_WRITE = "write"
+)
+
+var (
+	httpAuth = &HttpAuth{}
+)
+
+// Credential of basic auth user or bearer token
+type authCredential struct {
+	Name   string
+	Secret string
+	Scope  string
+}
+
+// Basic auth (--auth-file, bcrypt) and bearer tokens (--auth-token-file) for the http listener
+type HttpAuth struct {
+	mu     sync.RWMutex
+	users  map[string]authCredential
+	tokens []authCredential
+}
+
+// Load password and token files, previous credentials are kept on errors
+func (a *HttpAuth) Load(userFile string, tokenFile string) error {
+	users := map[string]authCredential{}
+	var tokens []authCredential
+
+	if userFile != "" {
+		credentials, err := parseAuthFile(userFile, true)
+		if err != nil {
+			return err
+		}
+		for _, credential := range credentials {
+			users[credential.Name] = credential
+		}
+	}
+
+	if tokenFile != "" {
+		credentials, err := parseAuthFile(tokenFile, false)
+		if err != nil {
+			return err
+		}
+		tokens = credentials
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.users = users
+	a.tokens = tokens
+
+	return nil
+}
+
+// Check if authentication is required
+func (a *HttpAuth) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.users) > 0 || len(a.tokens) > 0
+}
+
+// Return scope of request credentials (empty if not authenticated)
+func (a *HttpAuth) Authenticate(r *http.Request) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if username, password, ok := r.BasicAuth(); ok {
+		user, exists := a.users[username]
+		if exists && bcrypt.CompareHashAndPassword([]byte(user.Secret), []byte(password)) == nil {
+			return user.Scope
+		}
+		return ""
+	}
+
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		for _, credential := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(credential.Secret)) == 1 {
+				return credential.Scope
+			}
+		}
+	}
+
+	return ""
+}
+
+// Require read scope for GET/HEAD requests and write scope for mutating requests
+func (a *HttpAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		required := AUTH_SCOPE_READ
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			required = AUTH_SCOPE_WRITE
+		}
+
+		scope := a.Authenticate(r)
+		switch {
+		case scope == "":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, Name))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case required == AUTH_SCOPE_WRITE && scope != AUTH_SCOPE_WRITE:
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Parse auth file
+//
+// Password file: user:bcrypt-hash[:scope] (eg. created with htpasswd -B)
+// Token file:    token[:scope]
+//
+// Scope is read (default) or write.
+func parseAuthFile(path string, withName bool) ([]authCredential, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var ret []authCredential
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		split := strings.Split(line, ":")
+		credential := authCredential{Scope: AUTH_SCOPE_READ}
+
+		if withName {
+			if len(split) < 2 || len(split) > 3 || split[0] == "" || split[1] == "" {
+				return nil, fmt.Errorf("%s:%d: invalid line, expected user:bcrypt-hash[:scope]", path, lineNumber)
+			}
+			if _, err := bcrypt.Cost([]byte(split[1])); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid bcrypt hash for user %s", path, lineNumber, split[0])
+			}
+			credential.Name, credential.Secret, split = split[0], split[1], split[2:]
+		} else {
+			if len(split) > 2 || split[0] == "" {
+				return nil, fmt.Errorf("%s:%d: invalid line, expected token[:scope]", path, lineNumber)
+			}
+			credential.Name = fmt.Sprintf("token:%d", lineNumber)
+			credential.Secret, split = split[0], split[1:]
+		}
+
+		if len(split) == 1 {
+			switch split[0] {
+			case AUTH_SCOPE_READ, AUTH_SCOPE_WRITE:
+				credential.Scope = split[0]
+			default:
+				return nil, fmt.Errorf("%s:%d: invalid scope %q (read or write)", path, lineNumber, split[0])
+			}
+		}
+
+		ret = append(ret, credential)
+	}
+
+	return ret, scanner.Err()
+}
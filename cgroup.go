@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resource limits for the cgroup of a cronjob run (cgroup v2 interface values)
+type CgroupLimits struct {
+	MemoryMax string
+	CpuMax    string
+	PidsMax   string
+}
+
+// Return cgroup interface files and values, falling back to global defaults
+func (limits CgroupLimits) Files() map[string]string {
+	ret := map[string]string{}
+
+	memoryMax := limits.MemoryMax
+	if memoryMax == "" {
+		memoryMax = opts.CgroupMemoryMax
+	}
+
+	cpuMax := limits.CpuMax
+	if cpuMax == "" {
+		cpuMax = opts.CgroupCpuMax
+	}
+
+	pidsMax := limits.PidsMax
+	if pidsMax == "" {
+		pidsMax = opts.CgroupPidsMax
+	}
+
+	if memoryMax != "" {
+		ret["memory.max"] = memoryMax
+	}
+
+	if cpuMax != "" {
+		ret["cpu.max"] = cpuMax
+	}
+
+	if pidsMax != "" {
+		ret["pids.max"] = pidsMax
+	}
+
+	return ret
+}
+
+// Validate limits (max or bytes with K, M, G suffix for memory.max, "max [period]" for
+// cpu.max, max or number for pids.max)
+func (limits CgroupLimits) Validate() error {
+	if limits.MemoryMax != "" && limits.MemoryMax != "max" {
+		if _, err := parseByteSize(limits.MemoryMax); err != nil {
+			return fmt.Errorf("invalid memory.max %q (expected bytes with K, M or G suffix or max)", limits.MemoryMax)
+		}
+	}
+
+	if limits.CpuMax != "" {
+		fields := strings.Fields(limits.CpuMax)
+		valid := len(fields) == 1 || len(fields) == 2
+		if valid && fields[0] != "max" {
+			_, err := strconv.ParseUint(fields[0], 10, 64)
+			valid = err == nil
+		}
+		if valid && len(fields) == 2 {
+			_, err := strconv.ParseUint(fields[1], 10, 64)
+			valid = err == nil
+		}
+		if !valid {
+			return fmt.Errorf("invalid cpu.max %q (expected \"quota [period]\" in microseconds, quota can be max)", limits.CpuMax)
+		}
+	}
+
+	if limits.PidsMax != "" && limits.PidsMax != "max" {
+		if _, err := strconv.ParseUint(limits.PidsMax, 10, 64); err != nil {
+			return fmt.Errorf("invalid pids.max %q (expected number or max)", limits.PidsMax)
+		}
+	}
+
+	return nil
+}
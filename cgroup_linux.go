@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	CGROUP_ROOT = "/sys/fs/cgroup"
+)
+
+var (
+	// cgroup for cronjob runs, empty if cgroup support is disabled
+	cgroupJobsPath string
+	cgroupRunSeq   uint64
+)
+
+type cgroupRun struct {
+	path string
+	dir  *os.File
+}
+
+// Setup cgroup v2 sub-hierarchy for cronjobs, falls back to no cgroups on error
+func initCgroup() {
+	if !opts.Cgroup {
+		return
+	}
+
+	path, err := cgroupSetup()
+	if err != nil {
+		LoggerError.Printf("WARNING: cgroup v2 delegation not available, running cronjobs without cgroups: %v", err)
+		return
+	}
+
+	cgroupJobsPath = path
+	LoggerInfo.Printf("Using cgroup %s for cronjobs", path)
+}
+
+func cgroupSetup() (string, error) {
+	if !checkIfFileExists(filepath.Join(CGROUP_ROOT, "cgroup.controllers")) {
+		return "", fmt.Errorf("no cgroup v2 hierarchy mounted at %s", CGROUP_ROOT)
+	}
+
+	self, err := cgroupSelfPath()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(CGROUP_ROOT, self)
+
+	// move daemon into a leaf cgroup, controllers can only be
+	// delegated from cgroups without processes
+	daemonPath := filepath.Join(base, "daemon")
+	if err := os.MkdirAll(daemonPath, 0755); err != nil {
+		return "", err
+	}
+	if err := cgroupWrite(daemonPath, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return "", err
+	}
+
+	controllers, err := cgroupControllers(base)
+	if err != nil {
+		return "", err
+	}
+	if err := cgroupWrite(base, "cgroup.subtree_control", controllers); err != nil {
+		return "", err
+	}
+
+	jobsPath := filepath.Join(base, "jobs")
+	if err := os.MkdirAll(jobsPath, 0755); err != nil {
+		return "", err
+	}
+	if err := cgroupWrite(jobsPath, "cgroup.subtree_control", controllers); err != nil {
+		return "", err
+	}
+
+	return jobsPath, nil
+}
+
+// Return cgroup v2 path of current process
+func cgroupSelfPath() (string, error) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
+	}
+
+	return "", fmt.Errorf("no cgroup v2 entry found in /proc/self/cgroup")
+}
+
+// Return enable list of supported controllers (memory, cpu, pids) available in cgroup
+func cgroupControllers(path string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+
+	var ret []string
+	for _, controller := range strings.Fields(string(content)) {
+		switch controller {
+		case "memory", "cpu", "pids":
+			ret = append(ret, "+"+controller)
+		}
+	}
+
+	if len(ret) == 0 {
+		return "", fmt.Errorf("no memory, cpu or pids controller available in %s", path)
+	}
+
+	return strings.Join(ret, " "), nil
+}
+
+func cgroupWrite(path string, file string, value string) error {
+	return ioutil.WriteFile(filepath.Join(path, file), []byte(value), 0644)
+}
+
+// Create cgroup for cronjob run and let command start inside of it
+func cgroupPrepare(execCmd *exec.Cmd, id int, limits CgroupLimits) (*cgroupRun, error) {
+	if cgroupJobsPath == "" {
+		return nil, nil
+	}
+
+	name := fmt.Sprintf("job-%d-%d", id, atomic.AddUint64(&cgroupRunSeq, 1))
+	path := filepath.Join(cgroupJobsPath, name)
+
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, err
+	}
+
+	cg := &cgroupRun{path: path}
+
+	for file, value := range limits.Files() {
+		if err := cgroupWrite(path, file, value); err != nil {
+			cg.Close()
+			return nil, fmt.Errorf("cannot set %s=%s: %v", file, value, err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		cg.Close()
+		return nil, err
+	}
+	cg.dir = dir
+
+	if execCmd.SysProcAttr == nil {
+		execCmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	execCmd.SysProcAttr.UseCgroupFD = true
+	execCmd.SysProcAttr.CgroupFD = int(dir.Fd())
+
+	return cg, nil
+}
+
+// Remove cgroup of finished run, returns true if the run was killed by the OOM killer
+func (cg *cgroupRun) Close() bool {
+	if cg == nil {
+		return false
+	}
+
+	if cg.dir != nil {
+		cg.dir.Close()
+	}
+
+	oomKilled := false
+	if content, err := ioutil.ReadFile(filepath.Join(cg.path, "memory.events")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+				oomKilled = true
+			}
+		}
+	}
+
+	if err := os.Remove(cg.path); err != nil {
+		LoggerError.Printf("Cannot remove cgroup %s: %v", cg.path, err)
+	}
+
+	return oomKilled
+}
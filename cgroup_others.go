@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"os/exec"
+)
+
+type cgroupRun struct{}
+
+func initCgroup() {
+	if opts.Cgroup {
+		LoggerError.Println("WARNING: cgroups are not supported on this platform, running cronjobs without cgroups")
+	}
+}
+
+func cgroupPrepare(execCmd *exec.Cmd, id int, limits CgroupLimits) (*cgroupRun, error) {
+	return nil, nil
+}
+
+func (cg *cgroupRun) Close() bool {
+	return false
+}
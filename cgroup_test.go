@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCgroupLimitsValidate(t *testing.T) {
+	for _, test := range []struct {
+		limits CgroupLimits
+		valid  bool
+	}{
+		{CgroupLimits{}, true},
+		{CgroupLimits{MemoryMax: "512M", CpuMax: "50000 100000", PidsMax: "64"}, true},
+		{CgroupLimits{MemoryMax: "max", CpuMax: "max", PidsMax: "max"}, true},
+		{CgroupLimits{MemoryMax: "1073741824"}, true},
+		{CgroupLimits{CpuMax: "max 100000"}, true},
+		{CgroupLimits{CpuMax: "50000"}, true},
+		{CgroupLimits{MemoryMax: "512MB"}, false},
+		{CgroupLimits{MemoryMax: "-1"}, false},
+		{CgroupLimits{MemoryMax: "99999999999G"}, false},
+		{CgroupLimits{CpuMax: "50%"}, false},
+		{CgroupLimits{CpuMax: "50000 max"}, false},
+		{CgroupLimits{CpuMax: "50000 100000 1"}, false},
+		{CgroupLimits{CpuMax: " "}, false},
+		{CgroupLimits{PidsMax: "unlimited"}, false},
+		{CgroupLimits{PidsMax: "-1"}, false},
+	} {
+		if err := test.limits.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v): expected valid %v, got error %v", test.limits, test.valid, err)
+		}
+	}
+}
+
+func TestCgroupLimitsFiles(t *testing.T) {
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+	opts.CgroupMemoryMax = "1G"
+	opts.CgroupCpuMax = ""
+	opts.CgroupPidsMax = "128"
+
+	for _, test := range []struct {
+		limits   CgroupLimits
+		expected map[string]string
+	}{
+		{CgroupLimits{}, map[string]string{"memory.max": "1G", "pids.max": "128"}},
+		{CgroupLimits{MemoryMax: "256M", CpuMax: "max"}, map[string]string{"memory.max": "256M", "cpu.max": "max", "pids.max": "128"}},
+	} {
+		if files := test.limits.Files(); fmt.Sprint(files) != fmt.Sprint(test.expected) {
+			t.Errorf("Files(%+v): expected %v, got %v", test.limits, test.expected, files)
+		}
+	}
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+const (
+	CTL_COMMAND = "ctl"
+
+	// waiting time for stream of started run
+	CTL_STREAM_WAIT = 10 * time.Second
+
+	// started runs queued while following job logs
+	CTL_FOLLOW_QUEUE = 64
+
+	CTL_EXIT_ERROR = 1
+	CTL_EXIT_USAGE = 2
+)
+
+var ctlOpts struct {
+	Address  string `short:"a" long:"address"   env:"GO_CROND_ADDRESS"  description:"Address of go-crond api (http(s)://host:port or unix:/path)"  default:"http://localhost:9177"`
+	Token    string `          long:"token"     env:"GO_CROND_TOKEN"    description:"Bearer token"`
+	User     string `          long:"user"      env:"GO_CROND_USER"     description:"Basic auth credentials (user:password)"`
+	CACert   string `          long:"ca-cert"                           description:"CA file for verifying the server certificate"`
+	Cert     string `          long:"cert"                              description:"Client certificate file (mTLS)"`
+	Key      string `          long:"key"                               description:"Client key file (mTLS)"`
+	Insecure bool   `          long:"insecure"                          description:"Don't verify server certificate"`
+	Output   string `short:"o" long:"output"                            description:"Output format"  choice:"table"  choice:"json"  default:"table"`
+	Wait     bool   `short:"w" long:"wait"                              description:"run: wait for run to finish, exit with exit code of job"`
+	Follow   bool   `short:"f" long:"follow"                            description:"logs: follow output of current and next runs"`
+}
+
+const CTL_USAGE = `[OPTIONS] COMMAND [JOB]
+
+Commands:
+  list             List jobs
+  status JOB       Show job (id or name)
+  run JOB          Run job now (--wait: wait and exit with exit code of job)
+  pause JOB        Pause scheduled runs of job
+  resume JOB       Resume scheduled runs of job
+  reload           Reload crontabs
+  history JOB      Show runs of job
+  logs JOB         Show output of last run (--follow: follow current and next runs)`
+
+// Client of go-crond api
+type ctlClient struct {
+	base   string
+	client *http.Client
+}
+
+// Run go-crond ctl, returns exit code
+func ctlMain(args []string) int {
+	parser := flags.NewParser(&ctlOpts, flags.Default)
+	parser.Name = fmt.Sprintf("%s %s", Name, CTL_COMMAND)
+	parser.Usage = CTL_USAGE
+
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			return 0
+		}
+		return CTL_EXIT_USAGE
+	}
+
+	if len(args) == 0 {
+		parser.WriteHelp(os.Stderr)
+		return CTL_EXIT_USAGE
+	}
+
+	command, jobArgs := args[0], args[1:]
+
+	needsJob := command != "list" && command != "reload"
+	if needsJob && len(jobArgs) != 1 || !needsJob && len(jobArgs) != 0 {
+		parser.WriteHelp(os.Stderr)
+		return CTL_EXIT_USAGE
+	}
+
+	client, err := newCtlClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%v\n", LogPrefix, err)
+		return CTL_EXIT_ERROR
+	}
+
+	var job string
+	if needsJob {
+		job = url.PathEscape(jobArgs[0])
+	}
+
+	switch command {
+	case "list":
+		err = client.list()
+	case "status":
+		err = client.status(job)
+	case "run":
+		var exitCode int
+		if exitCode, err = client.run(job); err == nil {
+			return exitCode
+		}
+	case "pause":
+		err = client.pause(job, true)
+	case "resume":
+		err = client.pause(job, false)
+	case "reload":
+		err = client.reload()
+	case "history":
+		err = client.history(job)
+	case "logs":
+		err = client.logs(job)
+	default:
+		fmt.Fprintf(os.Stderr, "%sunknown command %q\n", LogPrefix, command)
+		return CTL_EXIT_USAGE
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%v\n", LogPrefix, err)
+		return CTL_EXIT_ERROR
+	}
+
+	return 0
+}
+
+func newCtlClient() (*ctlClient, error) {
+	transport := &http.Transport{}
+	ret := &ctlClient{base: strings.TrimSuffix(ctlOpts.Address, "/"), client: &http.Client{Transport: transport}}
+
+	if strings.HasPrefix(ctlOpts.Address, LISTEN_UNIX_PREFIX) {
+		path := strings.TrimPrefix(ctlOpts.Address, LISTEN_UNIX_PREFIX)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}
+		ret.base = "http://go-crond"
+		return ret, nil
+	}
+
+	if !strings.Contains(ret.base, "://") {
+		ret.base = "http://" + ret.base
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: ctlOpts.Insecure}
+
+	if ctlOpts.CACert != "" {
+		content, err := ioutil.ReadFile(ctlOpts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", ctlOpts.CACert)
+		}
+	}
+
+	if ctlOpts.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(ctlOpts.Cert, ctlOpts.Key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return ret, nil
+}
+
+// Send api request, json response is decoded into result
+func (c *ctlClient) request(method string, path string, result interface{}) error {
+	resp, err := c.send(method, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Send api request, returns error for non 2xx responses
+func (c *ctlClient) send(method string, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.base+API_PREFIX+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctlOpts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+ctlOpts.Token)
+	} else if ctlOpts.User != "" {
+		split := strings.SplitN(ctlOpts.User, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("invalid --user, expected user:password")
+		}
+		req.SetBasicAuth(split[0], split[1])
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		apiErr := ApiError{}
+		if json.Unmarshal(body, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(body))
+		}
+		return resp, &ctlHttpError{status: resp.StatusCode, message: apiErr.Error}
+	}
+
+	return resp, nil
+}
+
+type ctlHttpError struct {
+	status  int
+	message string
+}
+
+func (err *ctlHttpError) Error() string {
+	return fmt.Sprintf("%s (%d)", err.message, err.status)
+}
+
+// Read server-sent events, handler returns false to stop reading
+func (c *ctlClient) stream(path string, handler func(event string, data []byte) bool) error {
+	resp, err := c.send(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	event := ""
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case bytes.HasPrefix(line, []byte("event: ")):
+			event = string(line[len("event: "):])
+		case bytes.HasPrefix(line, []byte("data: ")):
+			if !handler(event, line[len("data: "):]) {
+				return nil
+			}
+		}
+	}
+}
+
+// Stream output of run to stdout/stderr, returns finished event
+func (c *ctlClient) streamRun(runId uint64) (*StreamEvent, error) {
+	var finished *StreamEvent
+	deadline := time.Now().Add(CTL_STREAM_WAIT)
+
+	for {
+		err := c.stream(fmt.Sprintf("runs/%d/stream", runId), func(eventType string, data []byte) bool {
+			event := StreamEvent{}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return true
+			}
+
+			switch event.Type {
+			case STREAM_EVENT_OUTPUT:
+				if ctlOpts.Output == "json" {
+					fmt.Println(string(data))
+				} else if event.Stream == "stderr" {
+					fmt.Fprintln(os.Stderr, event.Line)
+				} else {
+					fmt.Println(event.Line)
+				}
+			case STREAM_EVENT_FINISHED:
+				finished = &event
+				return false
+			}
+			return true
+		})
+
+		// run is started in background, stream might not exist yet
+		if httpErr, ok := err.(*ctlHttpError); ok && httpErr.status == http.StatusNotFound && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		if finished == nil {
+			return nil, fmt.Errorf("stream of run %d ended unexpectedly", runId)
+		}
+		return finished, nil
+	}
+}
+
+func (c *ctlClient) list() error {
+	var jobs []ApiJob
+	if err := c.request(http.MethodGet, "jobs", &jobs); err != nil {
+		return err
+	}
+
+	if ctlOpts.Output == "json" {
+		return ctlPrintJson(jobs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSPEC\tUSER\tSTATE\tNEXT\tLAST RUN\tCOMMAND")
+	for _, job := range jobs {
+		lastRun := "-"
+		if job.LastRun != nil {
+			lastRun = ctlFormatTime(&job.LastRun.Start)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", job.Id, ctlValue(job.Name), job.Spec, job.User, ctlJobState(job), ctlFormatTime(job.Next), lastRun, job.Command)
+	}
+
+	return w.Flush()
+}
+
+func (c *ctlClient) status(job string) error {
+	ret := ApiJob{}
+	if err := c.request(http.MethodGet, "jobs/"+job, &ret); err != nil {
+		return err
+	}
+
+	if ctlOpts.Output == "json" {
+		return ctlPrintJson(ret)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Id:\t%d\n", ret.Id)
+	fmt.Fprintf(w, "Name:\t%s\n", ctlValue(ret.Name))
+	fmt.Fprintf(w, "Command:\t%s\n", ret.Command)
+	fmt.Fprintf(w, "Spec:\t%s\n", ret.Spec)
+	fmt.Fprintf(w, "Schedule:\t%s\n", ctlValue(describeSchedule(ret.Spec)))
+	fmt.Fprintf(w, "User:\t%s\n", ret.User)
+	fmt.Fprintf(w, "Source:\t%s\n", ctlValue(ret.Source))
+	fmt.Fprintf(w, "Tags:\t%s\n", ctlValue(strings.Join(ret.Tags, ", ")))
+	fmt.Fprintf(w, "State:\t%s\n", ctlJobState(ret))
+	fmt.Fprintf(w, "Next run:\t%s\n", ctlFormatTime(ret.Next))
+	fmt.Fprintf(w, "Previous run:\t%s\n", ctlFormatTime(ret.Prev))
+	if ret.LastRun != nil {
+		fmt.Fprintf(w, "Last run:\t#%d %s (%s)\n", ret.LastRun.Id, ctlRunResult(*ret.LastRun), ctlFormatDuration(ret.LastRun.Duration))
+	}
+
+	return w.Flush()
+}
+
+// Run job, returns exit code of job if --wait is set
+func (c *ctlClient) run(job string) (int, error) {
+	started := ApiRunStarted{}
+	if err := c.request(http.MethodPost, "jobs/"+job+"/run", &started); err != nil {
+		return 0, err
+	}
+
+	if !ctlOpts.Wait {
+		if ctlOpts.Output == "json" {
+			return 0, ctlPrintJson(started)
+		}
+		fmt.Printf("Started run %d of job %d\n", started.RunId, started.JobId)
+		return 0, nil
+	}
+
+	finished, err := c.streamRun(started.RunId)
+	if err != nil {
+		return 0, err
+	}
+
+	if ctlOpts.Output == "json" {
+		if err := ctlPrintJson(finished); err != nil {
+			return 0, err
+		}
+	}
+
+	exitCode := 0
+	if finished.ExitCode != nil {
+		exitCode = *finished.ExitCode
+	}
+	if exitCode < 0 || exitCode > 255 {
+		// killed or not started
+		exitCode = CTL_EXIT_ERROR
+	}
+
+	return exitCode, nil
+}
+
+func (c *ctlClient) pause(job string, paused bool) error {
+	ret := ApiJob{}
+	action := "resume"
+	if paused {
+		action = "pause"
+	}
+
+	if err := c.request(http.MethodPost, "jobs/"+job+"/"+action, &ret); err != nil {
+		return err
+	}
+
+	if ctlOpts.Output == "json" {
+		return ctlPrintJson(ret)
+	}
+
+	if paused {
+		fmt.Printf("Paused job %d\n", ret.Id)
+	} else {
+		fmt.Printf("Resumed job %d\n", ret.Id)
+	}
+	return nil
+}
+
+func (c *ctlClient) reload() error {
+	ret := ReloadResult{}
+	if err := c.request(http.MethodPost, "reload", &ret); err != nil {
+		return err
+	}
+
+	if ctlOpts.Output == "json" {
+		if err := ctlPrintJson(ret); err != nil {
+			return err
+		}
+	} else {
+		for _, job := range ret.Added {
+			fmt.Printf("+ %s %s %s (%s)\n", job.Spec, ctlValue(job.User), job.Command, ctlValue(job.Source))
+		}
+		for _, job := range ret.Removed {
+			fmt.Printf("- %s %s %s (%s)\n", job.Spec, ctlValue(job.User), job.Command, ctlValue(job.Source))
+		}
+		if ret.Applied {
+			fmt.Printf("Loaded %d jobs (%d added, %d removed, %d unchanged)\n", ret.Jobs, len(ret.Added), len(ret.Removed), ret.Unchanged)
+		} else {
+			fmt.Printf("Not applied (%d added, %d removed, %d unchanged), keeping %d jobs\n", len(ret.Added), len(ret.Removed), ret.Unchanged, ret.Jobs)
+		}
+		for _, message := range ret.Errors {
+			fmt.Fprintf(os.Stderr, "%s%s\n", LogPrefix, message)
+		}
+	}
+
+	if !ret.Applied {
+		return fmt.Errorf("reload failed with %d errors, previous cron jobs are kept", len(ret.Errors))
+	}
+	if !ret.Success {
+		return fmt.Errorf("loaded with %d errors", len(ret.Errors))
+	}
+	return nil
+}
+
+func (c *ctlClient) history(job string) error {
+	var runs []ApiRun
+	if err := c.request(http.MethodGet, "jobs/"+job+"/runs", &runs); err != nil {
+		return err
+	}
+
+	if ctlOpts.Output == "json" {
+		return ctlPrintJson(runs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTARTED\tDURATION\tRESULT\tERROR")
+	for _, run := range runs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", run.Id, ctlFormatTime(&run.Start), ctlFormatDuration(run.Duration), ctlRunResult(run), ctlValue(run.Error))
+	}
+
+	return w.Flush()
+}
+
+// Show output of last run, with --follow current and next runs are streamed
+func (c *ctlClient) logs(job string) error {
+	ret := ApiJob{}
+	if err := c.request(http.MethodGet, "jobs/"+job, &ret); err != nil {
+		return err
+	}
+
+	if !ctlOpts.Follow {
+		if ret.LastRun == nil {
+			return nil
+		}
+
+		if ret.LastRun.Running {
+			_, err := c.streamRun(ret.LastRun.Id)
+			return err
+		}
+
+		run := ApiRun{}
+		if err := c.request(http.MethodGet, fmt.Sprintf("runs/%d", ret.LastRun.Id), &run); err != nil {
+			return err
+		}
+		if run.Output != nil {
+			fmt.Print(*run.Output)
+		}
+		return nil
+	}
+
+	if ret.LastRun != nil && ret.LastRun.Running {
+		if _, err := c.streamRun(ret.LastRun.Id); err != nil {
+			return err
+		}
+	}
+
+	// wait for next runs of job, the event stream is read while runs are streamed
+	// (slow subscribers are dropped by the daemon)
+	runs := make(chan uint64, CTL_FOLLOW_QUEUE)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- c.stream("events", func(eventType string, data []byte) bool {
+			event := Event{}
+			if err := json.Unmarshal(data, &event); err != nil || event.Type != EVENT_STARTED || event.JobId == nil || *event.JobId != ret.Id {
+				return true
+			}
+
+			select {
+			case runs <- event.RunId:
+			default:
+				fmt.Fprintf(os.Stderr, "%sskipping run %d, too many runs queued\n", LogPrefix, event.RunId)
+			}
+			return true
+		})
+	}()
+
+	for {
+		select {
+		case runId := <-runs:
+			if _, err := c.streamRun(runId); err != nil {
+				fmt.Fprintf(os.Stderr, "%s%v\n", LogPrefix, err)
+			}
+		case err := <-streamErr:
+			if err == nil {
+				err = fmt.Errorf("event stream ended unexpectedly")
+			}
+			return err
+		}
+	}
+}
+
+func ctlPrintJson(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func ctlJobState(job ApiJob) string {
+	if job.Paused {
+		return job.State + " (paused)"
+	}
+	return job.State
+}
+
+func ctlRunResult(run ApiRun) string {
+	switch {
+	case run.Running:
+		return "running"
+	case run.TimedOut:
+		return "timeout"
+	case run.OOMKilled:
+		return "oom killed"
+	case run.Error != "":
+		return fmt.Sprintf("exit %d", run.ExitCode)
+	default:
+		return "ok"
+	}
+}
+
+func ctlFormatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(UI_TIME_FORMAT)
+}
+
+func ctlFormatDuration(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
+
+func ctlValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
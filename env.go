@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	ENV_POLICY_INHERIT = "inherit"
+	ENV_POLICY_CLEAN   = "clean"
+
+	ENV_MASK = "***"
+)
+
+// Policy for passing the daemon environment to cronjobs
+type EnvPolicy struct {
+	Policy string
+	Allow  []string
+	Deny   []string
+}
+
+// Check if policy and patterns are valid
+func (p EnvPolicy) Validate() error {
+	switch p.Policy {
+	case "", ENV_POLICY_INHERIT, ENV_POLICY_CLEAN:
+	default:
+		return fmt.Errorf("invalid environment policy %q (expected %s or %s)", p.Policy, ENV_POLICY_INHERIT, ENV_POLICY_CLEAN)
+	}
+
+	for _, pattern := range append(p.Allow, p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid environment pattern %q: %v", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// Filter environment (NAME=value list) by policy, global options are used as defaults
+func (p EnvPolicy) Filter(environ []string) []string {
+	policy := p.Policy
+	if policy == "" {
+		policy = opts.EnvPolicy
+	}
+	allow := append(append([]string{}, opts.EnvAllow...), p.Allow...)
+	deny := append(append([]string{}, opts.EnvDeny...), p.Deny...)
+
+	ret := []string{}
+	for _, env := range environ {
+		name := strings.SplitN(env, "=", 2)[0]
+
+		if policy == ENV_POLICY_CLEAN && !envNameMatches(name, allow) {
+			continue
+		}
+
+		if envNameMatches(name, deny) {
+			continue
+		}
+
+		ret = append(ret, env)
+	}
+
+	return ret
+}
+
+func envNameMatches(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Environment of cronjob: filtered daemon environment, login environment and crontab variables
+func cronjobEnvironment(cronjob CrontabEntry, loginEnv []string) []string {
+	ret := cronjob.EnvPolicy.Filter(os.Environ())
+	ret = append(ret, loginEnv...)
+	ret = append(ret, cronjob.Env...)
+	return ret
+}
+
+// Return sorted variable names of environment with masked values
+func maskEnvironment(environ []string) []string {
+	names := map[string]bool{}
+	for _, env := range environ {
+		names[strings.SplitN(env, "=", 2)[0]] = true
+	}
+
+	ret := []string{}
+	for name := range names {
+		ret = append(ret, name+"="+ENV_MASK)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnvPolicyValidate(t *testing.T) {
+	for _, test := range []struct {
+		policy EnvPolicy
+		valid  bool
+	}{
+		{EnvPolicy{}, true},
+		{EnvPolicy{Policy: ENV_POLICY_INHERIT, Deny: []string{"AWS_*"}}, true},
+		{EnvPolicy{Policy: ENV_POLICY_CLEAN, Allow: []string{"TZ", "LC_?", "LANG"}}, true},
+		{EnvPolicy{Policy: "none"}, false},
+		{EnvPolicy{Allow: []string{"[A-"}}, false},
+		{EnvPolicy{Deny: []string{"SECRET_["}}, false},
+	} {
+		if err := test.policy.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v): expected valid %v, got error %v", test.policy, test.valid, err)
+		}
+	}
+}
+
+func TestEnvPolicyFilter(t *testing.T) {
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+
+	environ := []string{"PATH=/usr/bin", "TZ=UTC", "LANG=C", "AWS_SECRET_ACCESS_KEY=secret", "DB_PASSWORD=a=b", "EMPTY"}
+
+	for _, test := range []struct {
+		name     string
+		global   EnvPolicy
+		policy   EnvPolicy
+		expected []string
+	}{
+		{
+			"inherit",
+			EnvPolicy{Policy: ENV_POLICY_INHERIT},
+			EnvPolicy{},
+			environ,
+		},
+		{
+			"inherit with deny",
+			EnvPolicy{Policy: ENV_POLICY_INHERIT, Deny: []string{"AWS_*"}},
+			EnvPolicy{Deny: []string{"*_PASSWORD"}},
+			[]string{"PATH=/usr/bin", "TZ=UTC", "LANG=C", "EMPTY"},
+		},
+		{
+			"clean",
+			EnvPolicy{Policy: ENV_POLICY_CLEAN, Allow: []string{"PATH"}},
+			EnvPolicy{Allow: []string{"TZ"}},
+			[]string{"PATH=/usr/bin", "TZ=UTC"},
+		},
+		{
+			"clean from crontab",
+			EnvPolicy{Policy: ENV_POLICY_INHERIT},
+			EnvPolicy{Policy: ENV_POLICY_CLEAN, Allow: []string{"LANG", "DB_*"}},
+			[]string{"LANG=C", "DB_PASSWORD=a=b"},
+		},
+		{
+			"deny overrides allow",
+			EnvPolicy{Policy: ENV_POLICY_CLEAN, Allow: []string{"*"}, Deny: []string{"AWS_*", "DB_*"}},
+			EnvPolicy{},
+			[]string{"PATH=/usr/bin", "TZ=UTC", "LANG=C", "EMPTY"},
+		},
+		{
+			"inherit from crontab",
+			EnvPolicy{Policy: ENV_POLICY_CLEAN},
+			EnvPolicy{Policy: ENV_POLICY_INHERIT, Deny: []string{"EMPTY"}},
+			[]string{"PATH=/usr/bin", "TZ=UTC", "LANG=C", "AWS_SECRET_ACCESS_KEY=secret", "DB_PASSWORD=a=b"},
+		},
+	} {
+		opts.EnvPolicy = test.global.Policy
+		opts.EnvAllow = test.global.Allow
+		opts.EnvDeny = test.global.Deny
+
+		if filtered := test.policy.Filter(environ); fmt.Sprint(filtered) != fmt.Sprint(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, filtered)
+		}
+	}
+}
+
+func TestMaskEnvironment(t *testing.T) {
+	masked := maskEnvironment([]string{"TZ=UTC", "DB_PASSWORD=secret", "TZ=CET", "EMPTY"})
+	expected := []string{"DB_PASSWORD=***", "EMPTY=***", "TZ=***"}
+
+	if fmt.Sprint(masked) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, masked)
+	}
+}
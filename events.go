@@ -0,0 +1,191 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EVENT_SCHEDULED = "scheduled"
+	EVENT_STARTED   = "started"
+	EVENT_SUCCEEDED = "succeeded"
+	EVENT_FAILED    = "failed"
+	EVENT_SKIPPED   = "skipped"
+	EVENT_TIMED_OUT = "timed-out"
+	EVENT_RELOADED  = "reloaded"
+
+	// slow subscribers are dropped if their buffer is full
+	EVENT_SUBSCRIBER_BUFFER = 256
+
+	// event ids start at the start time of the daemon (seconds) shifted by these bits,
+	// cursors of previous daemon runs can be detected (ids stay below 2^53 for json clients)
+	EVENT_ID_EPOCH_SHIFT = 20
+)
+
+var (
+	eventLog = NewEventLog(1000)
+)
+
+// Lifecycle event of daemon or cronjob (GET /api/v1/events)
+type Event struct {
+	Id       uint64    `json:"id"` // sequence number (starting at epoch of daemon run), used as resume cursor
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	JobId    *int      `json:"job_id,omitempty"`
+	JobName  string    `json:"job_name,omitempty"`
+	RunId    uint64    `json:"run_id,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"` // succeeded, failed, timed-out
+	Error    string    `json:"error,omitempty"`     // failed, skipped, reloaded
+	Duration float64   `json:"duration,omitempty"`  // seconds
+	Jobs     *int      `json:"jobs,omitempty"`      // number of loaded jobs (reloaded)
+}
+
+// Ring buffer of last events with subscribers
+type EventLog struct {
+	mu          sync.Mutex
+	size        int
+	events      []Event
+	firstId     uint64
+	nextId      uint64
+	subscribers map[chan Event]bool
+}
+
+func NewEventLog(size int) *EventLog {
+	firstId := uint64(time.Now().Unix()) << EVENT_ID_EPOCH_SHIFT
+	return &EventLog{
+		size:        size,
+		firstId:     firstId,
+		nextId:      firstId,
+		subscribers: map[chan Event]bool{},
+	}
+}
+
+// Set number of buffered events (--event-buffer, negative sizes are treated as 0)
+func (l *EventLog) SetSize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+
+	l.size = size
+	l.trim()
+}
+
+func (l *EventLog) trim() {
+	if len(l.events) > l.size {
+		l.events = append([]Event{}, l.events[len(l.events)-l.size:]...)
+	}
+}
+
+// Add event and send it to subscribers
+func (l *EventLog) Publish(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Id = l.nextId
+	l.nextId++
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	l.events = append(l.events, event)
+	l.trim()
+
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// subscriber too slow, client has to reconnect with cursor
+			delete(l.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Subscribe to events, with resume buffered events after cursor (id of last received event) are returned
+//
+// Returns buffered events after cursor, channel for new events and number of missed
+// events (dropped from the buffer). Cursors of previous daemon runs return all buffered
+// events, the unknown number of events since the cursor is counted as one missed event.
+func (l *EventLog) Subscribe(cursor uint64, resume bool) ([]Event, chan Event, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var backlog []Event
+	var missed uint64
+
+	if resume && (cursor < l.firstId || cursor >= l.nextId) {
+		backlog = append(backlog, l.events...)
+
+		missed = 1
+		if len(l.events) > 0 {
+			missed += l.events[0].Id - l.firstId
+		} else {
+			missed += l.nextId - l.firstId
+		}
+	} else if resume {
+		for _, event := range l.events {
+			if event.Id > cursor {
+				backlog = append(backlog, event)
+			}
+		}
+
+		oldest := l.nextId
+		if len(l.events) > 0 {
+			oldest = l.events[0].Id
+		}
+		if cursor+1 < oldest {
+			missed = oldest - cursor - 1
+		}
+	}
+
+	subscriber := make(chan Event, EVENT_SUBSCRIBER_BUFFER)
+	l.subscribers[subscriber] = true
+
+	return backlog, subscriber, missed
+}
+
+func (l *EventLog) Unsubscribe(subscriber chan Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.subscribers[subscriber] {
+		delete(l.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// Publish event of cronjob run
+func publishJobEvent(eventType string, id int, runId uint64, cronjob CrontabEntry, err error, elapsed time.Duration) {
+	event := Event{
+		Type:     eventType,
+		JobId:    &id,
+		JobName:  redactor.Redact(cronjob.Name),
+		RunId:    runId,
+		Duration: elapsed.Seconds(),
+	}
+
+	switch eventType {
+	case EVENT_SUCCEEDED, EVENT_FAILED, EVENT_TIMED_OUT:
+		code := exitCode(err)
+		event.ExitCode = &code
+	}
+
+	if err != nil {
+		event.Error = redactor.Redact(err.Error())
+	}
+
+	eventLog.Publish(event)
+}
+
+// Publish event of (re)loaded crontabs
+func publishReloadEvent(jobs int, err error) {
+	event := Event{Type: EVENT_RELOADED, Jobs: &jobs}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	eventLog.Publish(event)
+}
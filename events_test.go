@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testEventIds(events []Event) []uint64 {
+	ids := []uint64{}
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	return ids
+}
+
+func TestEventLogSubscribe(t *testing.T) {
+	log := NewEventLog(3)
+	first := log.nextId
+	for i := 0; i < 5; i++ {
+		log.Publish(Event{Type: EVENT_STARTED})
+	}
+
+	// buffered events: first+2 ... first+4
+	for _, test := range []struct {
+		name    string
+		cursor  uint64
+		resume  bool
+		backlog []uint64
+		missed  uint64
+	}{
+		{"no resume", 0, false, []uint64{}, 0},
+		{"latest", first + 4, true, []uint64{}, 0},
+		{"buffered", first + 2, true, []uint64{first + 3, first + 4}, 0},
+		{"oldest buffered", first + 1, true, []uint64{first + 2, first + 3, first + 4}, 0},
+		{"dropped", first, true, []uint64{first + 2, first + 3, first + 4}, 1},
+		{"previous run", first - 100, true, []uint64{first + 2, first + 3, first + 4}, 3},
+		{"unknown run", first + 1000, true, []uint64{first + 2, first + 3, first + 4}, 3},
+	} {
+		backlog, events, missed := log.Subscribe(test.cursor, test.resume)
+		log.Unsubscribe(events)
+
+		if ids := testEventIds(backlog); fmt.Sprint(ids) != fmt.Sprint(test.backlog) {
+			t.Errorf("%s: expected backlog %v, got %v", test.name, test.backlog, ids)
+		}
+		if missed != test.missed {
+			t.Errorf("%s: expected %d missed events, got %d", test.name, test.missed, missed)
+		}
+	}
+}
+
+func TestEventLogPreviousRunWithoutEvents(t *testing.T) {
+	log := NewEventLog(3)
+
+	backlog, events, missed := log.Subscribe(log.nextId-5, true)
+	log.Unsubscribe(events)
+
+	if len(backlog) != 0 || missed != 1 {
+		t.Errorf("expected no backlog and 1 missed event, got %v and %d", testEventIds(backlog), missed)
+	}
+}
+
+func TestEventLogSetSize(t *testing.T) {
+	log := NewEventLog(10)
+	for i := 0; i < 5; i++ {
+		log.Publish(Event{Type: EVENT_STARTED})
+	}
+
+	for _, test := range []struct {
+		size     int
+		expected int
+	}{
+		{10, 5},
+		{2, 2},
+		{-1, 0},
+	} {
+		log.SetSize(test.size)
+		if len(log.events) != test.expected {
+			t.Errorf("SetSize(%d): expected %d events, got %d", test.size, test.expected, len(log.events))
+		}
+	}
+}
+
+func TestEventLogSlowSubscriber(t *testing.T) {
+	log := NewEventLog(10)
+
+	_, events, _ := log.Subscribe(0, false)
+	for i := 0; i < EVENT_SUBSCRIBER_BUFFER+1; i++ {
+		log.Publish(Event{Type: EVENT_STARTED})
+	}
+
+	// buffered events are delivered, then the channel is closed
+	received := 0
+	for range events {
+		received++
+	}
+	if received != EVENT_SUBSCRIBER_BUFFER {
+		t.Errorf("expected %d events before close, got %d", EVENT_SUBSCRIBER_BUFFER, received)
+	}
+}
@@ -2,24 +2,27 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 )
 
-func fileGetAbsolutePath(path string) (string, os.FileInfo) {
+func fileGetAbsolutePath(path string) (string, os.FileInfo, error) {
 	ret, err := filepath.Abs(path)
 	if err != nil {
-		LoggerError.Fatalf("Invalid file: %v", err)
+		return "", nil, fmt.Errorf("invalid file: %v", err)
 	}
 
 	f, err := os.Lstat(ret)
 	if err != nil {
-		LoggerError.Fatalf("File stats failed: %v", err)
+		return "", nil, fmt.Errorf("file stats failed: %v", err)
 	}
 
-	return ret, f
+	return ret, f, nil
 }
 
 func checkIfFileExists(path string) bool {
@@ -87,3 +90,62 @@ func checkIfFileIsValid(f os.FileInfo, path string) bool {
 
 	return false
 }
+
+// Parse size in bytes (number with optional K, M, G suffix)
+func parseByteSize(value string) (uint64, error) {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1 << 30
+	}
+	number := value
+	if multiplier > 1 {
+		number = value[:len(value)-1]
+	}
+
+	ret, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	if ret > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+
+	return ret * multiplier, nil
+}
+
+// Check if value is a valid http(s) url
+func httpUrlValidate(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("must be a http or https url")
+	}
+
+	return nil
+}
+
+// Split list separated by comma or whitespace
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// Check if list contains value
+func stringInSlice(value string, list []string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	HEARTBEAT_INTERVAL = 10 * time.Second
+
+	// scheduler is considered stalled if the last heartbeat is older
+	HEARTBEAT_MAX_AGE = 3 * HEARTBEAT_INTERVAL
+
+	HEALTH_PATH    = "/healthz"
+	READINESS_PATH = "/readyz"
+)
+
+var (
+	heartbeat = &Heartbeat{}
+)
+
+// Heartbeat of scheduler, ticks every HEARTBEAT_INTERVAL while the runner is started
+type Heartbeat struct {
+	mu        sync.RWMutex
+	running   bool
+	last      time.Time
+	fileError string
+}
+
+// GET /healthz, GET /readyz
+type ApiHealth struct {
+	Status string            `json:"status"` // ok or failed
+	Checks map[string]string `json:"checks"` // ok or error message
+}
+
+func (h *Heartbeat) Started() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.running = true
+	h.last = time.Now()
+}
+
+func (h *Heartbeat) Stopped() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.running = false
+}
+
+// Record tick of scheduler and touch --heartbeat-file
+func (h *Heartbeat) Tick() {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = now
+
+	if opts.HeartbeatFile == "" {
+		return
+	}
+
+	// log errors only once (every tick would flood the log)
+	fileError := ""
+	if err := touchFile(opts.HeartbeatFile, now); err != nil {
+		fileError = err.Error()
+		if fileError != h.fileError {
+			LoggerError.Printf("Cannot touch heartbeat file: %v", err)
+		}
+	}
+	h.fileError = fileError
+}
+
+// Check if scheduler is ticking (also during reloads)
+func (h *Heartbeat) CheckAlive() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.last.IsZero() {
+		return nil
+	}
+
+	if age := time.Since(h.last); age > HEARTBEAT_MAX_AGE {
+		return fmt.Errorf("scheduler stalled, last heartbeat %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Check if scheduler is started and ticking
+func (h *Heartbeat) CheckStarted() error {
+	h.mu.RLock()
+	running := h.running
+	h.mu.RUnlock()
+
+	if !running {
+		return fmt.Errorf("scheduler not started")
+	}
+	return h.CheckAlive()
+}
+
+// Check if crontabs were loaded without errors
+func checkCrontabsLoaded() error {
+	daemonStatus.mu.RLock()
+	defer daemonStatus.mu.RUnlock()
+
+	if daemonStatus.LastReload.IsZero() {
+		return fmt.Errorf("crontabs not loaded")
+	}
+	return daemonStatus.LastReloadError
+}
+
+// Serve /healthz and /readyz (without authentication, for probes of orchestrators)
+func probeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var checks map[string]error
+
+		switch r.URL.Path {
+		case HEALTH_PATH:
+			checks = map[string]error{
+				"scheduler": heartbeat.CheckAlive(),
+			}
+		case READINESS_PATH:
+			checks = map[string]error{
+				"crontabs":  checkCrontabsLoaded(),
+				"scheduler": heartbeat.CheckStarted(),
+			}
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := http.StatusOK
+		ret := ApiHealth{Status: "ok", Checks: map[string]string{}}
+		for name, err := range checks {
+			ret.Checks[name] = "ok"
+			if err != nil {
+				ret.Checks[name] = err.Error()
+				ret.Status = "failed"
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		apiWriteJson(w, status, ret)
+	})
+}
+
+// Create file or update its modification time
+func touchFile(path string, t time.Time) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return os.Chtimes(path, t, t)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// max size of stored output per run
+	RUN_OUTPUT_MAX_SIZE = 64 * 1024
+)
+
+// Run of cronjob (kept in run history)
+type Run struct {
+	Id        uint64
+	JobId     int
+	Start     time.Time
+	End       time.Time
+	Running   bool
+	ExitCode  int
+	Error     string
+	TimedOut  bool
+	OOMKilled bool
+	Elapsed   time.Duration
+	Output    string
+}
+
+// Ring buffer of last runs of all cronjobs (--run-history), survives reloads
+type RunHistory struct {
+	mu   sync.RWMutex
+	size int
+	runs []*Run
+	next int
+}
+
+func NewRunHistory(size int) *RunHistory {
+	return &RunHistory{size: size}
+}
+
+// Add started run, oldest run is dropped if history is full
+func (h *RunHistory) Start(jobId int, runId uint64, start time.Time) {
+	if h.size <= 0 {
+		return
+	}
+
+	run := &Run{Id: runId, JobId: jobId, Start: start, Running: true}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.runs) < h.size {
+		h.runs = append(h.runs, run)
+	} else {
+		h.runs[h.next] = run
+	}
+	h.next = (h.next + 1) % h.size
+}
+
+// Set result of finished run
+func (h *RunHistory) Finish(runId uint64, output string, err error, elapsed time.Duration, timedOut bool, oomKilled bool) {
+	if len(output) > RUN_OUTPUT_MAX_SIZE {
+		output = output[len(output)-RUN_OUTPUT_MAX_SIZE:]
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, run := range h.runs {
+		if run.Id == runId {
+			run.Running = false
+			run.End = run.Start.Add(elapsed)
+			run.Elapsed = elapsed
+			run.ExitCode = exitCode(err)
+			run.TimedOut = timedOut
+			run.OOMKilled = oomKilled
+			run.Output = redactor.Redact(output)
+			if err != nil {
+				run.Error = redactor.Redact(err.Error())
+			}
+			return
+		}
+	}
+}
+
+// Return runs (newest first), jobId < 0 returns runs of all jobs
+func (h *RunHistory) List(jobId int) []Run {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var ret []Run
+	for i := 1; i <= len(h.runs); i++ {
+		run := h.runs[(h.next-i+len(h.runs))%len(h.runs)]
+		if jobId < 0 || run.JobId == jobId {
+			ret = append(ret, *run)
+		}
+	}
+
+	return ret
+}
+
+// Return run by id
+func (h *RunHistory) Get(runId uint64) (Run, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, run := range h.runs {
+		if run.Id == runId {
+			return *run, true
+		}
+	}
+
+	return Run{}, false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRunIds(runs []Run) []uint64 {
+	ids := []uint64{}
+	for _, run := range runs {
+		ids = append(ids, run.Id)
+	}
+	return ids
+}
+
+func TestRunHistoryList(t *testing.T) {
+	start := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		size   int
+		runs   int
+		all    []uint64
+		jobOne []uint64
+	}{
+		{0, 3, []uint64{}, []uint64{}},
+		{5, 0, []uint64{}, []uint64{}},
+		{5, 3, []uint64{3, 2, 1}, []uint64{3, 1}},
+		{3, 3, []uint64{3, 2, 1}, []uint64{3, 1}},
+		{3, 7, []uint64{7, 6, 5}, []uint64{7, 5}},
+		{1, 4, []uint64{4}, []uint64{}},
+	} {
+		history := NewRunHistory(test.size)
+		for i := 1; i <= test.runs; i++ {
+			// odd runs are runs of job 1, even runs of job 2
+			history.Start(2-i%2, uint64(i), start.Add(time.Duration(i)*time.Minute))
+		}
+
+		if ids := testRunIds(history.List(-1)); fmt.Sprint(ids) != fmt.Sprint(test.all) {
+			t.Errorf("size %d, %d runs: expected runs %v, got %v", test.size, test.runs, test.all, ids)
+		}
+		if ids := testRunIds(history.List(1)); fmt.Sprint(ids) != fmt.Sprint(test.jobOne) {
+			t.Errorf("size %d, %d runs: expected runs of job 1 %v, got %v", test.size, test.runs, test.jobOne, ids)
+		}
+		if ids := testRunIds(history.List(3)); len(ids) != 0 {
+			t.Errorf("size %d, %d runs: expected no runs of job 3, got %v", test.size, test.runs, ids)
+		}
+	}
+}
+
+func TestRunHistoryFinish(t *testing.T) {
+	start := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	history := NewRunHistory(2)
+	history.Start(1, 1, start)
+	history.Start(1, 2, start)
+
+	if run, ok := history.Get(1); !ok || !run.Running {
+		t.Fatalf("expected running run 1, got %+v (%v)", run, ok)
+	}
+
+	output := strings.Repeat("a", RUN_OUTPUT_MAX_SIZE) + "end"
+	history.Finish(1, output, errors.New("killed"), time.Minute, true, false)
+
+	run, ok := history.Get(1)
+	if !ok {
+		t.Fatal("expected finished run 1")
+	}
+	if run.Running || !run.TimedOut || run.ExitCode != -1 || run.Error != "killed" {
+		t.Errorf("unexpected result of run 1: running %v, timed out %v, exit code %d, error %q", run.Running, run.TimedOut, run.ExitCode, run.Error)
+	}
+	if !run.End.Equal(start.Add(time.Minute)) || run.Elapsed != time.Minute {
+		t.Errorf("unexpected end %v and elapsed %v of run 1", run.End, run.Elapsed)
+	}
+	if len(run.Output) != RUN_OUTPUT_MAX_SIZE || !strings.HasSuffix(run.Output, "end") {
+		t.Errorf("expected end of output truncated to %d bytes, got %d bytes", RUN_OUTPUT_MAX_SIZE, len(run.Output))
+	}
+
+	// returned runs are copies
+	run.Output = ""
+	if stored, _ := history.Get(1); stored.Output == "" {
+		t.Error("expected stored run to be unchanged")
+	}
+
+	// runs dropped from history are ignored
+	history.Start(1, 3, start)
+	history.Finish(1, "", nil, time.Second, false, false)
+	if _, ok := history.Get(1); ok {
+		t.Error("expected run 1 to be dropped from history")
+	}
+	if run, _ := history.Get(2); !run.Running {
+		t.Error("expected run 2 to be still running")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	JOB_LOG_MODE_RUN    = "run"
+	JOB_LOG_MODE_APPEND = "append"
+
+	JOB_LOG_TIME_FORMAT = "2006-01-02T15-04-05.000"
+	JOB_LOG_SUFFIX      = ".log"
+
+	JOB_LOG_CLEANUP_INTERVAL = 1 * time.Hour
+)
+
+// Return log directory of job (job ids are stable across reloads and restarts)
+func jobLogPath(id int) string {
+	return filepath.Join(opts.JobLogDir, strconv.Itoa(id))
+}
+
+// Handler for job log files (/logs/), output is always served as plain text
+//
+// Output of cronjobs may contain untrusted data, it must never be rendered as html
+// (directory listings are still html, file names are escaped).
+func jobLogHandler() http.Handler {
+	files := http.StripPrefix("/logs/", http.FileServer(http.Dir(opts.JobLogDir)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// Write output of cronjob run to the job log directory (--job-log-dir)
+//
+// Mode run creates one file per run, mode append appends to the newest file
+// until it exceeds --job-log-max-size or --job-log-max-age.
+func writeJobLog(id int, runId uint64, start time.Time, output string, err error, elapsed time.Duration) error {
+	if opts.JobLogDir == "" {
+		return nil
+	}
+
+	dir := jobLogPath(id)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, start.Format(JOB_LOG_TIME_FORMAT)+JOB_LOG_SUFFIX)
+	content := output
+
+	if opts.JobLogMode == JOB_LOG_MODE_APPEND {
+		if current, ok := jobLogCurrentFile(dir); ok {
+			path = current
+		}
+
+		content = fmt.Sprintf("--- %s run:%d exit:%d time:%s\n%s", start.Format(time.RFC3339), runId, exitCode(err), elapsed, output)
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	}
+
+	content = redactor.Redact(content)
+
+	file, ferr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if ferr != nil {
+		return ferr
+	}
+
+	if _, werr := file.WriteString(content); werr != nil {
+		file.Close()
+		return werr
+	}
+
+	if cerr := file.Close(); cerr != nil {
+		return cerr
+	}
+
+	jobLogCleanup(dir)
+	return nil
+}
+
+// Return newest log file of job directory if it can still be appended
+func jobLogCurrentFile(dir string) (string, bool) {
+	files := jobLogFiles(dir)
+	if len(files) == 0 {
+		return "", false
+	}
+
+	current := files[len(files)-1]
+
+	if opts.JobLogMaxAge > 0 {
+		created, err := time.ParseInLocation(JOB_LOG_TIME_FORMAT, strings.TrimSuffix(current.Name(), JOB_LOG_SUFFIX), time.Local)
+		if err != nil || time.Since(created) >= opts.JobLogMaxAge {
+			return "", false
+		}
+	}
+
+	if opts.JobLogMaxSize != "" {
+		maxSize, _ := parseByteSize(opts.JobLogMaxSize)
+		if uint64(current.Size()) >= maxSize {
+			return "", false
+		}
+	}
+
+	return filepath.Join(dir, current.Name()), true
+}
+
+// Return log files of job directory (oldest first)
+func jobLogFiles(dir string) []os.FileInfo {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var ret []os.FileInfo
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && strings.HasSuffix(entry.Name(), JOB_LOG_SUFFIX) {
+			ret = append(ret, entry)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name() < ret[j].Name() })
+	return ret
+}
+
+// Remove log files exceeding --job-log-keep or older than --job-log-max-age
+func jobLogCleanup(dir string) {
+	files := jobLogFiles(dir)
+
+	for i, file := range files {
+		expired := opts.JobLogKeep > 0 && i < len(files)-opts.JobLogKeep
+		if opts.JobLogMaxAge > 0 && i < len(files)-1 && time.Since(file.ModTime()) >= opts.JobLogMaxAge {
+			expired = true
+		}
+
+		if expired {
+			if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+				LoggerError.Printf("Cannot remove job log %s: %v", file.Name(), err)
+			}
+		}
+	}
+}
+
+// Apply retention to all directories of --job-log-dir
+//
+// Directories of jobs which no longer exist are removed completely after
+// --job-log-max-age, empty directories are removed.
+func jobLogCleanupAll(jobs []Job) {
+	if opts.JobLogDir == "" {
+		return
+	}
+
+	active := map[string]bool{}
+	for _, job := range jobs {
+		active[strconv.Itoa(job.Id)] = true
+	}
+
+	entries, err := ioutil.ReadDir(opts.JobLogDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(opts.JobLogDir, entry.Name())
+		if active[entry.Name()] {
+			jobLogCleanup(dir)
+			continue
+		}
+
+		if opts.JobLogMaxAge > 0 {
+			for _, file := range jobLogFiles(dir) {
+				if time.Since(file.ModTime()) >= opts.JobLogMaxAge {
+					if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+						LoggerError.Printf("Cannot remove job log %s: %v", file.Name(), err)
+					}
+				}
+			}
+		}
+
+		// fails if directory is not empty
+		os.Remove(dir)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJobLogHandlerPlainText(t *testing.T) {
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+
+	opts.JobLogDir = t.TempDir()
+	dir := filepath.Join(opts.JobLogDir, "42")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	// output of cronjob looking like html
+	if err := ioutil.WriteFile(filepath.Join(dir, "2017-06-01T02-03-04.000.log"), []byte("<html><script>alert(1)</script>"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/logs/42/2017-06-01T02-03-04.000.log", 200, "text/plain; charset=utf-8"},
+		{"/logs/42/", 200, "text/html; charset=utf-8"},
+		{"/logs/42/missing.log", 404, "text/plain; charset=utf-8"},
+	} {
+		w := httptest.NewRecorder()
+		jobLogHandler().ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.path, test.status, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s: expected content type %q, got %q", test.path, test.contentType, contentType)
+		}
+		if nosniff := w.Header().Get("X-Content-Type-Options"); nosniff != "nosniff" {
+			t.Errorf("%s: expected X-Content-Type-Options nosniff, got %q", test.path, nosniff)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// field prefix for structured fields (eg. CRON_JOB_NAME)
+	JOURNALD_FIELD_PREFIX = "CRON_"
+
+	// size of output field if message is too big for a datagram
+	JOURNALD_TRUNCATE_SIZE = 16 * 1024
+)
+
+var (
+	journaldFieldCleanupRegexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+)
+
+// Log sink for journald (native protocol)
+type JournaldSink struct {
+	conn *net.UnixConn
+}
+
+func NewJournaldSink(path string) (*JournaldSink, error) {
+	addr := &net.UnixAddr{Name: path, Net: "unixgram"}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournaldSink{conn: conn}, nil
+}
+
+func (sink *JournaldSink) Write(record LogRecord) {
+	_, err := sink.conn.Write(sink.Format(record, 0))
+	if err != nil {
+		// message too big for a datagram, retry with truncated fields
+		_, err = sink.conn.Write(sink.Format(record, JOURNALD_TRUNCATE_SIZE))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%scannot write to journald: %v\n", LogPrefix, err)
+	}
+}
+
+// Serialize record in journald native protocol, values are truncated if limit is set
+func (sink *JournaldSink) Format(record LogRecord, limit int) []byte {
+	var buf bytes.Buffer
+
+	writeField := func(key string, value string) {
+		if limit > 0 && len(value) > limit {
+			value = value[:limit] + "\n[truncated]"
+		}
+
+		if strings.Contains(value, "\n") {
+			// binary safe format: KEY\n<uint64 le length><value>\n
+			buf.WriteString(key)
+			buf.WriteString("\n")
+			binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+			buf.WriteString(value)
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(key)
+			buf.WriteString("=")
+			buf.WriteString(value)
+			buf.WriteString("\n")
+		}
+	}
+
+	writeField("MESSAGE", formatTextRecord(record))
+	writeField("PRIORITY", fmt.Sprint(syslogSeverity(record.Level)))
+	writeField("SYSLOG_IDENTIFIER", SYSLOG_TAG)
+	writeField(JOURNALD_FIELD_PREFIX+"EVENT", record.Message)
+	for _, field := range record.Fields {
+		writeField(journaldFieldName(field.Key), fmt.Sprint(field.Value))
+	}
+
+	return buf.Bytes()
+}
+
+// Journald field name of structured field (eg. job_name -> CRON_JOB_NAME)
+func journaldFieldName(key string) string {
+	return JOURNALD_FIELD_PREFIX + journaldFieldCleanupRegexp.ReplaceAllString(strings.ToUpper(key), "_")
+}
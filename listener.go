@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// disables listener
+	LISTEN_OFF = "off"
+
+	// prefix of unix socket listen addresses (eg. unix:/run/go-crond.sock)
+	LISTEN_UNIX_PREFIX = "unix:"
+)
+
+// Return listen address of component, empty addresses default to --listen-address
+func componentListenAddress(address string) string {
+	if address == "" {
+		return opts.ListenAddress
+	}
+	return address
+}
+
+// Listen on tcp address or unix socket (unix:/path, with --unix-socket-mode and --unix-socket-owner)
+func httpListen(address string) (net.Listener, error) {
+	if !strings.HasPrefix(address, LISTEN_UNIX_PREFIX) {
+		return net.Listen("tcp", address)
+	}
+
+	path := strings.TrimPrefix(address, LISTEN_UNIX_PREFIX)
+
+	// remove stale socket of previous run (if nobody is listening on it)
+	if stat, err := os.Lstat(path); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is already in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := strconv.ParseUint(opts.UnixSocketMode, 8, 32)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("invalid unix socket mode %q: %v", opts.UnixSocketMode, err)
+	}
+
+	if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	if opts.UnixSocketOwner != "" {
+		owner, err := resolveIdentity(opts.UnixSocketOwner)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+
+		if err := os.Chown(path, int(owner.Uid), int(owner.Gid)); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+// Serve handler on address (with tls if --tls-cert is set, unix sockets are served without tls)
+func listenAndServe(address string, handler http.Handler) {
+	listener, err := httpListen(address)
+	if err != nil {
+		LoggerError.Printf("Cannot listen on %s: %v", address, err)
+		return
+	}
+
+	server := &http.Server{Handler: handler}
+
+	if opts.TlsCert != "" && !strings.HasPrefix(address, LISTEN_UNIX_PREFIX) {
+		server.TLSConfig = httpTls.Config()
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+
+	if err != nil {
+		LoggerError.Printf("Cannot serve on %s: %v", address, err)
+	}
+}
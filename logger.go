@@ -1,31 +1,264 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	LOG_FORMAT_TEXT   = "text"
+	LOG_FORMAT_JSON   = "json"
+	LOG_FORMAT_LOGFMT = "logfmt"
+)
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
 var (
 	LoggerInfo  CronLogger
 	LoggerError CronLogger
+
+	logMu       sync.Mutex
+	logLevel    = LevelInfo
+	logFormat   = LOG_FORMAT_TEXT
+	logSinks    = []LogSink{&stdioSink{}}
+	logLevelMap = map[string]LogLevel{
+		"debug": LevelDebug,
+		"info":  LevelInfo,
+		"warn":  LevelWarn,
+		"error": LevelError,
+	}
 )
 
 func initLogger() {
-	LoggerInfo = CronLogger{log.New(os.Stdout, LogPrefix, 0)}
-	LoggerError = CronLogger{log.New(os.Stderr, LogPrefix, 0)}
+	LoggerInfo = CronLogger{log.New(&logWriter{level: LevelInfo}, "", 0), LevelInfo}
+	LoggerError = CronLogger{log.New(&logWriter{level: LevelError}, "", 0), LevelError}
+}
+
+// Apply log options (after argument parsing)
+func setupLogger() {
+	logFormat = opts.LogFormat
+	logLevel = logLevelMap[opts.LogLevel]
+
+	// --verbose
+	if opts.Verbose {
+		logLevel = LevelDebug
+	}
+
+	// --log-target
+	var sinks []LogSink
+	for _, target := range opts.LogTarget {
+		switch target {
+		case "stdout":
+			sinks = append(sinks, &stdioSink{})
+		case "syslog":
+			sink, err := NewSyslogSink(opts.SyslogAddress, opts.SyslogFormat, opts.SyslogFacility)
+			if err != nil {
+				LoggerError.Fatalf("Invalid syslog configuration: %v", err)
+			}
+			sinks = append(sinks, sink)
+		case "journald":
+			sink, err := NewJournaldSink(opts.JournaldSocket)
+			if err != nil {
+				LoggerError.Fatalf("Cannot connect to journald: %v", err)
+			}
+			sinks = append(sinks, sink)
+		}
+	}
+
+	logMu.Lock()
+	logSinks = sinks
+	logMu.Unlock()
+}
+
+func (level LogLevel) String() string {
+	for name, value := range logLevelMap {
+		if value == level {
+			return name
+		}
+	}
+	return "info"
+}
+
+// Structured log field
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Structured log record
+type LogRecord struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Fields  []LogField
+
+	// message for text format (instead of message and fields)
+	Text string
+}
+
+// Return value of field
+func (record LogRecord) Field(key string) (interface{}, bool) {
+	for _, field := range record.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Writer for free text messages of *log.Logger, every write is one record
+type logWriter struct {
+	level LogLevel
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	logRecord(LogRecord{Time: time.Now(), Level: w.level, Message: strings.TrimRight(string(p), "\n")})
+	return len(p), nil
+}
+
+// Destination of log records
+type LogSink interface {
+	Write(record LogRecord)
+}
+
+// Emit log record to all sinks
+func logRecord(record LogRecord) {
+	if record.Level < logLevel {
+		return
+	}
+
+	record = redactLogRecord(record)
+
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	for _, sink := range logSinks {
+		sink.Write(record)
+	}
+}
+
+// Log sink for stdout (and stderr for errors) in configured format
+type stdioSink struct{}
+
+func (sink *stdioSink) Write(record LogRecord) {
+	var stream io.Writer = os.Stdout
+	if record.Level >= LevelError {
+		stream = os.Stderr
+	}
+
+	switch logFormat {
+	case LOG_FORMAT_JSON:
+		stream.Write(formatJsonRecord(record))
+	case LOG_FORMAT_LOGFMT:
+		stream.Write(formatLogfmtRecord(record))
+	default:
+		stream.Write([]byte(LogPrefix + formatTextRecord(record) + "\n"))
+	}
+}
+
+// Text representation of record (without prefix and trailing newline)
+func formatTextRecord(record LogRecord) string {
+	var buf bytes.Buffer
+
+	if record.Text != "" {
+		buf.WriteString(strings.TrimRight(record.Text, "\n"))
+	} else {
+		buf.WriteString(record.Message)
+		for _, field := range record.Fields {
+			fmt.Fprintf(&buf, " %s=%v", field.Key, field.Value)
+		}
+	}
+
+	return buf.String()
+}
+
+func formatJsonRecord(record LogRecord) []byte {
+	var buf bytes.Buffer
+
+	writeField := func(key string, value interface{}) {
+		if buf.Len() == 0 {
+			buf.WriteString("{")
+		} else {
+			buf.WriteString(",")
+		}
+
+		encodedKey, _ := json.Marshal(key)
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			encodedValue, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(encodedKey)
+		buf.WriteString(":")
+		buf.Write(encodedValue)
+	}
+
+	writeField("time", record.Time.Format(time.RFC3339Nano))
+	writeField("level", record.Level.String())
+	writeField("msg", record.Message)
+	for _, field := range record.Fields {
+		writeField(field.Key, field.Value)
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func formatLogfmtRecord(record LogRecord) []byte {
+	var buf bytes.Buffer
+
+	writeField := func(key string, value interface{}) {
+		if buf.Len() > 0 {
+			buf.WriteString(" ")
+		}
+
+		str := fmt.Sprint(value)
+		if str == "" || strings.ContainsAny(str, " =\"\t\r\n") {
+			str = strconv.Quote(str)
+		}
+		buf.WriteString(key)
+		buf.WriteString("=")
+		buf.WriteString(str)
+	}
+
+	writeField("time", record.Time.Format(time.RFC3339Nano))
+	writeField("level", record.Level.String())
+	writeField("msg", record.Message)
+	for _, field := range record.Fields {
+		writeField(field.Key, field.Value)
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
 }
 
 type CronLogger struct {
 	*log.Logger
+	level LogLevel
 }
 
 func (CronLogger CronLogger) Verbose(message string) {
-	if opts.Verbose {
-		CronLogger.Println(message)
-	}
+	logRecord(LogRecord{Time: time.Now(), Level: LevelDebug, Message: message})
+}
+
+// Emit structured event with level of logger
+func (CronLogger CronLogger) Event(message string, text string, fields ...LogField) {
+	logRecord(LogRecord{Time: time.Now(), Level: CronLogger.level, Message: message, Text: text, Fields: fields})
 }
 
 func (CronLogger CronLogger) CronjobToString(cronjob CrontabEntry) string {
@@ -38,24 +271,82 @@ func (CronLogger CronLogger) CronjobToString(cronjob CrontabEntry) string {
 	return strings.Join(parts, " ")
 }
 
-func (CronLogger CronLogger) CronjobAdd(cronjob CrontabEntry) {
-	CronLogger.Printf("add: %v\n", CronLogger.CronjobToString(cronjob))
+// Structured fields of cronjob
+func cronjobLogFields(id int, runId uint64, cronjob CrontabEntry) []LogField {
+	fields := []LogField{
+		{"job_id", id},
+		{"job_name", cronjob.Name},
+		{"user", cronjob.User},
+		{"spec", cronjob.Spec},
+		{"command", cronjob.Command},
+	}
+
+	if runId > 0 {
+		fields = append(fields, LogField{"run_id", runId})
+	}
+
+	return fields
 }
 
-func (CronLogger CronLogger) CronjobExec(cronjob CrontabEntry) {
-	if opts.Verbose {
-		CronLogger.Printf("exec: %v\n", CronLogger.CronjobToString(cronjob))
+// Return exit code of finished command (-1 if it was not exited normally)
+func exitCode(err error) int {
+	if err == nil {
+		return 0
 	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+
+	return -1
 }
 
-func (CronLogger CronLogger) CronjobExecFailed(cronjob CrontabEntry, output string, err error, elapsed time.Duration) {
-	CronLogger.Printf("failed cronjob: cmd:%v err:%v time:%s\n%v\n", cronjob.Command, err, elapsed, output)
+func (CronLogger CronLogger) CronjobAdd(id int, cronjob CrontabEntry) {
+	CronLogger.Event("cronjob added", fmt.Sprintf("add: %v", CronLogger.CronjobToString(cronjob)),
+		cronjobLogFields(id, 0, cronjob)...)
 }
 
-func (CronLogger CronLogger) CronjobExecSuccess(cronjob CrontabEntry, output string, err error, elapsed time.Duration) {
-	if opts.Verbose {
-		CronLogger.Printf("ok: cronjob: cmd:%v err:%v time:%s\n%v\n", cronjob.Command, err, elapsed, output)
-	} else {
-		CronLogger.Printf("%v\n", output)
+func (CronLogger CronLogger) CronjobExec(id int, runId uint64, cronjob CrontabEntry) {
+	logRecord(LogRecord{Time: time.Now(), Level: LevelDebug, Message: "cronjob started",
+		Text: fmt.Sprintf("exec: %v", CronLogger.CronjobToString(cronjob)), Fields: cronjobLogFields(id, runId, cronjob)})
+}
+
+func (CronLogger CronLogger) CronjobExecFailed(id int, runId uint64, cronjob CrontabEntry, output string, err error, elapsed time.Duration) {
+	fields := cronjobLogFields(id, runId, cronjob)
+	fields = append(fields,
+		LogField{"duration", elapsed.Seconds()},
+		LogField{"exit_code", exitCode(err)},
+		LogField{"error", fmt.Sprint(err)},
+		LogField{"output", output},
+	)
+
+	CronLogger.Event("cronjob failed", fmt.Sprintf("failed cronjob: cmd:%v err:%v time:%s\n%v", cronjob.Command, err, elapsed, output), fields...)
+}
+
+func (CronLogger CronLogger) CronjobExecSuccess(id int, runId uint64, cronjob CrontabEntry, output string, err error, elapsed time.Duration) {
+	fields := cronjobLogFields(id, runId, cronjob)
+	fields = append(fields,
+		LogField{"duration", elapsed.Seconds()},
+		LogField{"exit_code", exitCode(err)},
+		LogField{"output", output},
+	)
+
+	// output of cronjob (summary in fields), runs without output are only logged with debug level
+	level, text := CronLogger.level, output
+	if output == "" {
+		level = LevelDebug
+	}
+	if logLevel <= LevelDebug {
+		text = fmt.Sprintf("ok: cronjob: cmd:%v err:%v time:%s\n%v", cronjob.Command, err, elapsed, output)
 	}
+
+	logRecord(LogRecord{Time: time.Now(), Level: level, Message: "cronjob succeeded", Text: text, Fields: fields})
+}
+
+func (CronLogger CronLogger) Signal(sig os.Signal) {
+	CronLogger.Event("signal received", fmt.Sprintf("Got signal: %v", sig), LogField{"signal", sig.String()})
+}
+
+func (CronLogger CronLogger) Reload() {
+	CronLogger.Event("reloading configuration", "Reloading configuration")
 }
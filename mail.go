@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// timeout for connecting and for the whole smtp session
+	SMTP_DIAL_TIMEOUT    = 30 * time.Second
+	SMTP_SESSION_TIMEOUT = 2 * time.Minute
+)
+
+// Mail settings of cronjob (MAILTO and MAILFROM)
+type MailConfig struct {
+	To    string
+	ToSet bool
+	From  string
+}
+
+// Return recipients, MAILTO="" disables mails
+func (config MailConfig) recipients() []string {
+	to := opts.MailTo
+	if config.ToSet {
+		to = config.To
+	}
+
+	return splitList(to)
+}
+
+func (config MailConfig) sender() string {
+	if config.From != "" {
+		return config.From
+	}
+
+	if opts.MailFrom != "" {
+		return opts.MailFrom
+	}
+
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s", Name, hostname)
+}
+
+// Send output of cronjob run by mail (in background, only if there is output)
+func mailCronjobOutput(cronjob CrontabEntry, output string) {
+	if output == "" {
+		return
+	}
+
+	recipients := cronjob.Mail.recipients()
+	if len(recipients) == 0 {
+		return
+	}
+
+	if opts.SmtpServer == "" {
+		LoggerInfo.Verbose(fmt.Sprintf("Not sending mail to %s, no --smtp-server configured", strings.Join(recipients, ", ")))
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	from := cronjob.Mail.sender()
+
+	// standard cron subject and headers
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: Cron <%s@%s> %s\r\n", cronjob.User, hostname, mailHeaderValue(redactor.Redact(cronjob.Command)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&msg, "Auto-Submitted: auto-generated\r\n")
+	fmt.Fprintf(&msg, "\r\n")
+	msg.WriteString(mailBody(redactor.Redact(output)))
+
+	go func() {
+		if err := sendMail(from, recipients, msg.Bytes()); err != nil {
+			LoggerError.Printf("Cannot send mail for cron job %v: %v", LoggerError.CronjobToString(cronjob), err)
+		}
+	}()
+}
+
+// Convert line breaks of body to CRLF (also if output already contains CRLF)
+func mailBody(body string) string {
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	return strings.Replace(body, "\n", "\r\n", -1)
+}
+
+// Remove line breaks from header value
+func mailHeaderValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Send mail with smtp relay (--smtp-server), uses STARTTLS if available
+func sendMail(from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(opts.SmtpServer)
+	if err != nil {
+		return fmt.Errorf("invalid smtp server %q: %v", opts.SmtpServer, err)
+	}
+
+	conn, err := net.DialTimeout("tcp", opts.SmtpServer, SMTP_DIAL_TIMEOUT)
+	if err != nil {
+		return err
+	}
+
+	// don't hang on stalled smtp servers
+	if err := conn.SetDeadline(time.Now().Add(SMTP_SESSION_TIMEOUT)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	} else if opts.SmtpRequireTLS {
+		return fmt.Errorf("smtp server %s does not support STARTTLS", opts.SmtpServer)
+	}
+
+	if opts.SmtpUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", opts.SmtpUsername, opts.SmtpPassword, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(msg); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
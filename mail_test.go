@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Mail received by fake smtp server
+type testMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Start fake smtp server (without STARTTLS and AUTH) accepting one mail
+func testSmtpServer(t *testing.T) (string, chan testMail) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan testMail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		mail := testMail{}
+
+		text.PrintfLine("220 localhost ESMTP test")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL":
+				mail.From = line
+				text.PrintfLine("250 ok")
+			case "RCPT":
+				mail.To = append(mail.To, line)
+				text.PrintfLine("250 ok")
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				mail.Data = string(data)
+				text.PrintfLine("250 ok")
+				mails <- mail
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				return
+			default:
+				text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), mails
+}
+
+func testMailOpts(t *testing.T, server string) {
+	t.Helper()
+
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+
+	opts.SmtpServer = server
+	opts.SmtpRequireTLS = false
+	opts.SmtpUsername = ""
+	opts.MailTo = ""
+	opts.MailFrom = ""
+}
+
+func waitForMail(t *testing.T, mails chan testMail) testMail {
+	t.Helper()
+
+	select {
+	case mail := <-mails:
+		return mail
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	return testMail{}
+}
+
+func TestMailCronjobOutput(t *testing.T) {
+	initLogger()
+	server, mails := testSmtpServer(t)
+	testMailOpts(t, server)
+
+	cronjob := CrontabEntry{
+		User:    "root",
+		Command: "/usr/local/bin/backup\n--all",
+		Mail:    MailConfig{To: "ops@example.com, dev@example.com", ToSet: true, From: "cron@example.com"},
+	}
+	mailCronjobOutput(cronjob, "line 1\nline 2\n")
+
+	mail := waitForMail(t, mails)
+
+	// envelope
+	if mail.From != "MAIL FROM:<cron@example.com>" {
+		t.Errorf("unexpected sender %q", mail.From)
+	}
+	expectedTo := []string{"RCPT TO:<ops@example.com>", "RCPT TO:<dev@example.com>"}
+	if fmt.Sprint(mail.To) != fmt.Sprint(expectedTo) {
+		t.Errorf("expected recipients %v, got %v", expectedTo, mail.To)
+	}
+
+	// headers
+	header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(mail.Data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]string{
+		"From":           "cron@example.com",
+		"To":             "ops@example.com, dev@example.com",
+		"Content-Type":   "text/plain; charset=UTF-8",
+		"Auto-Submitted": "auto-generated",
+	} {
+		if header.Get(key) != expected {
+			t.Errorf("expected header %s %q, got %q", key, expected, header.Get(key))
+		}
+	}
+
+	// line breaks of command are removed from subject
+	if subject := header.Get("Subject"); !strings.HasPrefix(subject, "Cron <root@") || !strings.HasSuffix(subject, "> /usr/local/bin/backup --all") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	if _, err := time.Parse(time.RFC1123Z, header.Get("Date")); err != nil {
+		t.Errorf("invalid date header: %v", err)
+	}
+
+	// body
+	if !strings.HasSuffix(mail.Data, "\n\nline 1\nline 2\n") {
+		t.Errorf("unexpected body %q", mail.Data)
+	}
+}
+
+func TestMailCronjobOutputDefaults(t *testing.T) {
+	initLogger()
+	server, mails := testSmtpServer(t)
+	testMailOpts(t, server)
+	opts.MailTo = "admin@example.com"
+	opts.MailFrom = "go-crond@example.com"
+
+	mailCronjobOutput(CrontabEntry{User: "root", Command: "true"}, "output")
+
+	mail := waitForMail(t, mails)
+	if mail.From != "MAIL FROM:<go-crond@example.com>" {
+		t.Errorf("unexpected sender %q", mail.From)
+	}
+	if fmt.Sprint(mail.To) != "[RCPT TO:<admin@example.com>]" {
+		t.Errorf("unexpected recipients %v", mail.To)
+	}
+}
+
+func TestMailCronjobOutputDisabled(t *testing.T) {
+	initLogger()
+	server, mails := testSmtpServer(t)
+	testMailOpts(t, server)
+	opts.MailTo = "admin@example.com"
+
+	// MAILTO="" disables mails, no mails without output
+	mailCronjobOutput(CrontabEntry{User: "root", Command: "true", Mail: MailConfig{ToSet: true}}, "output")
+	mailCronjobOutput(CrontabEntry{User: "root", Command: "true"}, "")
+
+	select {
+	case mail := <-mails:
+		t.Errorf("unexpected mail %v", mail)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSendMailRequireTLS(t *testing.T) {
+	server, _ := testSmtpServer(t)
+	testMailOpts(t, server)
+	opts.SmtpRequireTLS = true
+
+	if err := sendMail("cron@example.com", []string{"ops@example.com"}, []byte("test")); err == nil {
+		t.Error("expected error for smtp server without STARTTLS")
+	}
+}
+
+func TestMailBody(t *testing.T) {
+	for _, test := range []struct {
+		output   string
+		expected string
+	}{
+		{"", ""},
+		{"line", "line"},
+		{"line 1\nline 2\n", "line 1\r\nline 2\r\n"},
+		{"line 1\r\nline 2\r\n", "line 1\r\nline 2\r\n"},
+		{"line 1\r\nline 2\nline 3\r", "line 1\r\nline 2\r\nline 3\r"},
+	} {
+		if body := mailBody(test.output); body != test.expected {
+			t.Errorf("mailBody(%q): expected %q, got %q", test.output, test.expected, body)
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
-	"text/template"
+	"time"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/prometheus/client_golang/prometheus"
@@ -27,37 +27,72 @@ const (
 	CRONTAB_TYPE_SYSTEM = ""
 )
 
-type tplCronListParams struct {
-	Cronjobs []Job
-}
-
-const CRONLIST = `
-<html>
-<head><title>Cron Explorer</title></head>
-<body>
-<h1>Cron Explorer</h1>
-<p><table>
-{{range .Cronjobs}}
-<tr><td><b>{{.Name}}</b></td><td>err:{{.Status}}</td><td>Last run second: {{.Elapsed}}</td></tr>
-{{end}}
-</table></p>
-</body>
-</html>`
-
 var opts struct {
-	DefaultUser         string   `           long:"default-user"         description:"Default user"                  default:"root"`
-	IncludeCronD        []string `           long:"include"              description:"Include files in directory as system crontabs (with user)"`
-	NoAuto              bool     `           long:"no-auto"              description:"Disable automatic system crontab detection"`
-	RunParts            []string `           long:"run-parts"            description:"Execute files in directory with custom spec (like run-parts; spec-units:ns,us,s,m,h; format:time-spec:path; eg:10s,1m,1h30m)"`
-	RunParts1m          []string `           long:"run-parts-1min"       description:"Execute files in directory every beginning minute (like run-parts)"`
-	RunParts15m         []string `           long:"run-parts-15min"      description:"Execute files in directory every beginning 15 minutes (like run-parts)"`
-	RunPartsHourly      []string `           long:"run-parts-hourly"     description:"Execute files in directory every beginning hour (like run-parts)"`
-	RunPartsDaily       []string `           long:"run-parts-daily"      description:"Execute files in directory every beginning day (like run-parts)"`
-	RunPartsWeekly      []string `           long:"run-parts-weekly"     description:"Execute files in directory every beginning week (like run-parts)"`
-	RunPartsMonthly     []string `           long:"run-parts-monthly"    description:"Execute files in directory every beginning month (like run-parts)"`
-	ListenAddress       string   `           long:"listen-address"       description:"Address to listen on for web interface and telemetry."  default:":9177"`
-	MetricsPath         string   `           long:"telemetry-path"       description:"Path under which to expose metrics."                    default:"/metrics"`
-	AllowUnprivileged   bool     `           long:"allow-unprivileged"   description:"Allow daemon to run as non root (unprivileged) user"`
+	DefaultUser         string        `           long:"default-user"         description:"Default user"                  default:"root"`
+	IncludeCronD        []string      `           long:"include"              description:"Include files in directory as system crontabs (with user)"`
+	NoAuto              bool          `           long:"no-auto"              description:"Disable automatic system crontab detection"`
+	RunParts            []string      `           long:"run-parts"            description:"Execute files in directory with custom spec (like run-parts; spec-units:ns,us,s,m,h; format:time-spec:path; eg:10s,1m,1h30m)"`
+	RunParts1m          []string      `           long:"run-parts-1min"       description:"Execute files in directory every beginning minute (like run-parts)"`
+	RunParts15m         []string      `           long:"run-parts-15min"      description:"Execute files in directory every beginning 15 minutes (like run-parts)"`
+	RunPartsHourly      []string      `           long:"run-parts-hourly"     description:"Execute files in directory every beginning hour (like run-parts)"`
+	RunPartsDaily       []string      `           long:"run-parts-daily"      description:"Execute files in directory every beginning day (like run-parts)"`
+	RunPartsWeekly      []string      `           long:"run-parts-weekly"     description:"Execute files in directory every beginning week (like run-parts)"`
+	RunPartsMonthly     []string      `           long:"run-parts-monthly"    description:"Execute files in directory every beginning month (like run-parts)"`
+	ListenAddress       string        `           long:"listen-address"       description:"Address to listen on for web interface, api and telemetry (unix:/path for unix socket, off to disable)."  default:":9177"`
+	UiAddress           string        `           long:"ui-address"           description:"Address to listen on for web interface (default: --listen-address)"`
+	ApiAddress          string        `           long:"api-address"          description:"Address to listen on for api (default: --listen-address)"`
+	TelemetryAddress    string        `           long:"telemetry-address"    description:"Address to listen on for telemetry (default: --listen-address)"`
+	UnixSocketMode      string        `           long:"unix-socket-mode"     description:"File mode of unix sockets (octal)"  default:"0660"`
+	UnixSocketOwner     string        `           long:"unix-socket-owner"    description:"Owner of unix sockets (user, user:group or :group)"`
+	MetricsPath         string        `           long:"telemetry-path"       description:"Path under which to expose metrics."                    default:"/metrics"`
+	TlsCert             string        `           long:"tls-cert"             description:"TLS certificate file for web interface, api and telemetry (reloaded on SIGHUP)"`
+	TlsKey              string        `           long:"tls-key"              description:"TLS key file (reloaded on SIGHUP)"`
+	TlsClientCA         string        `           long:"tls-client-ca"        description:"Require client certificates signed by CA file (mTLS, reloaded on SIGHUP)"`
+	AuthFile            string        `           long:"auth-file"            description:"Basic auth users file (user:bcrypt-hash[:read|write], reloaded on SIGHUP)"`
+	AuthTokenFile       string        `           long:"auth-token-file"      description:"Bearer tokens file (token[:read|write], reloaded on SIGHUP)"`
+	AllowUnprivileged   bool          `           long:"allow-unprivileged"   description:"Allow daemon to run as non root (unprivileged) user"`
+	UserCacheTTL        time.Duration `           long:"user-cache-ttl"       description:"Duration for caching user and group lookups"  default:"15m"`
+	Cgroup              bool          `           long:"cgroup"               description:"Run each cronjob in its own cgroup (cgroup v2 delegation required)"`
+	CgroupMemoryMax     string        `           long:"cgroup-memory-max"    description:"Default memory.max for cronjob cgroups (eg: 512M, max)"`
+	CgroupCpuMax        string        `           long:"cgroup-cpu-max"       description:"Default cpu.max for cronjob cgroups (eg: \"50000 100000\", max)"`
+	CgroupPidsMax       string        `           long:"cgroup-pids-max"      description:"Default pids.max for cronjob cgroups (eg: 100, max)"`
+	EnvPolicy           string        `           long:"env-policy"           description:"Environment passed to cronjobs"  default:"inherit"  choice:"inherit"  choice:"clean"`
+	EnvAllow            []string      `           long:"env-allow"            description:"Pass environment variables matching pattern to cronjobs with env policy clean (eg: LANG, LC_*)"`
+	EnvDeny             []string      `           long:"env-deny"             description:"Do not pass environment variables matching pattern to cronjobs (eg: *_PASSWORD)"`
+	LogFormat           string        `           long:"log-format"           description:"Log format"  default:"text"  choice:"text"  choice:"json"  choice:"logfmt"`
+	LogLevel            string        `           long:"log-level"            description:"Log level"   default:"info"  choice:"debug"  choice:"info"  choice:"warn"  choice:"error"`
+	LogTarget           []string      `           long:"log-target"           description:"Log target (multiple possible)"  default:"stdout"  choice:"stdout"  choice:"syslog"  choice:"journald"`
+	SyslogAddress       string        `           long:"syslog-address"       description:"Syslog address (unix:path, udp:host:port or tcp:host:port)"  default:"unix:/dev/log"`
+	SyslogFormat        string        `           long:"syslog-format"        description:"Syslog message format"  default:"rfc3164"  choice:"rfc3164"  choice:"rfc5424"`
+	SyslogFacility      string        `           long:"syslog-facility"      description:"Syslog facility"  default:"cron"`
+	JournaldSocket      string        `           long:"journald-socket"      description:"Journald socket path"  default:"/run/systemd/journal/socket"`
+	JobLogDir           string        `           long:"job-log-dir"          description:"Write output of cronjobs to files in directory (<dir>/<job-id>/<timestamp>.log)"`
+	JobLogMode          string        `           long:"job-log-mode"         description:"Job log mode (run: one file per run, append: append to newest file)"  default:"run"  choice:"run"  choice:"append"`
+	JobLogMaxSize       string        `           long:"job-log-max-size"     description:"Start new job log file if size is exceeded (append mode; eg: 10M)"`
+	JobLogMaxAge        time.Duration `           long:"job-log-max-age"      description:"Start new job log file and remove job log files after duration (eg: 24h)"`
+	JobLogKeep          int           `           long:"job-log-keep"         description:"Number of job log files to keep per job (0: unlimited)"  default:"10"`
+	Watch               bool          `           long:"watch"                description:"Reload on changes of crontab files, include and run-parts directories (inotify)"`
+	WatchDebounce       time.Duration `           long:"watch-debounce"       description:"Wait for further changes before reloading (--watch)"  default:"1s"`
+	HeartbeatFile       string        `           long:"heartbeat-file"       description:"Touch file on every scheduler heartbeat (for exec probes)"`
+	RunHistory          int           `           long:"run-history"          description:"Number of cronjob runs (with output) kept in memory for the api"  default:"100"`
+	EventBuffer         int           `           long:"event-buffer"         description:"Number of events kept in memory for resuming the event stream"  default:"1000"`
+	Redact              []string      `           long:"redact"               description:"Mask regex matches in logs and web interface (only groups if pattern has groups; eg: \"Bearer (\\S+)\")"`
+	RedactEnv           []string      `           long:"redact-env"           description:"Mask values of environment variables matching pattern in logs and web interface (eg: *_TOKEN)"`
+	JobTimeout          time.Duration `           long:"job-timeout"          description:"Kill cronjobs after duration (eg: 1h; 0: no timeout)"`
+	NotifyWebhook       string        `           long:"notify-webhook"       description:"Send notifications for cronjob runs to webhook url (http post)"`
+	NotifyOn            []string      `           long:"notify-on"            description:"Events for notifications (multiple possible)"  default:"failure"  default:"timeout"  default:"recovery"  choice:"failure"  choice:"timeout"  choice:"recovery"  choice:"success"`
+	NotifyTemplate      string        `           long:"notify-template"      description:"Template file for notification body (text/template, default: json payload)"`
+	NotifyContentType   string        `           long:"notify-content-type"  description:"Content type of templated notification body"  default:"application/json"`
+	NotifyRetries       int           `           long:"notify-retries"       description:"Number of retries for failed notifications"  default:"3"`
+	NotifyOutputLines   int           `           long:"notify-output-lines"  description:"Number of output lines (tail) in notifications (0: all)"  default:"20"`
+	PingRetries         int           `           long:"ping-retries"         description:"Number of retries for failed pings (CROND_PING_URL)"  default:"3"`
+	PingOutputLines     int           `           long:"ping-output-lines"    description:"Number of output lines (tail) in fail pings (0: all)"  default:"20"`
+	MailTo              string        `           long:"mail-to"              description:"Send output of cronjobs to mail address if MAILTO is not set"`
+	MailFrom            string        `           long:"mail-from"            description:"Sender of mails if MAILFROM is not set (default: go-crond@hostname)"`
+	SmtpServer          string        `           long:"smtp-server"          description:"SMTP relay for mails (host:port)"`
+	SmtpUsername        string        `           long:"smtp-username"        description:"SMTP username"`
+	SmtpPassword        string        `           long:"smtp-password"        description:"SMTP password"  env:"SMTP_PASSWORD"`
+	SmtpRequireTLS      bool          `           long:"smtp-require-tls"     description:"Don't send mails if SMTP relay doesn't support STARTTLS"`
 	EnableUserSwitching bool
 	Verbose             bool `short:"v"  long:"verbose"              description:"verbose mode"`
 	ShowVersion         bool `short:"V"  long:"version"              description:"show version and exit"`
@@ -97,6 +132,46 @@ func initArgParser() []string {
 		os.Exit(1)
 	}
 
+	// --notify-template
+	if opts.NotifyTemplate != "" {
+		tmpl, err := loadNotifyTemplate(opts.NotifyTemplate)
+		if err != nil {
+			logFatalErrorAndExit(err, 1)
+		}
+		notifyDefaultTemplate = tmpl
+	}
+
+	// --notify-webhook
+	if err := notifyWebhookValidate(opts.NotifyWebhook); err != nil {
+		logFatalErrorAndExit(err, 1)
+	}
+
+	// --tls-cert, --tls-key
+	if (opts.TlsCert == "") != (opts.TlsKey == "") {
+		logFatalErrorAndExit(fmt.Errorf("--tls-cert and --tls-key have to be used together"), 1)
+	}
+	if opts.TlsClientCA != "" && opts.TlsCert == "" {
+		logFatalErrorAndExit(fmt.Errorf("--tls-client-ca requires --tls-cert and --tls-key"), 1)
+	}
+
+	// --cgroup-*-max
+	defaultCgroupLimits := CgroupLimits{MemoryMax: opts.CgroupMemoryMax, CpuMax: opts.CgroupCpuMax, PidsMax: opts.CgroupPidsMax}
+	if err := defaultCgroupLimits.Validate(); err != nil {
+		logFatalErrorAndExit(err, 1)
+	}
+
+	// --event-buffer
+	if opts.EventBuffer < 0 {
+		logFatalErrorAndExit(fmt.Errorf("--event-buffer must not be negative"), 1)
+	}
+
+	// --job-log-max-size
+	if opts.JobLogMaxSize != "" {
+		if _, err := parseByteSize(opts.JobLogMaxSize); err != nil {
+			logFatalErrorAndExit(err, 1)
+		}
+	}
+
 	return args
 }
 
@@ -110,10 +185,14 @@ func logFatalErrorAndExit(err error, exitCode int) {
 	os.Exit(exitCode)
 }
 
-func findFilesInPaths(pathlist []string, callback func(os.FileInfo, string)) {
+func findFilesInPaths(pathlist []string, callback func(os.FileInfo, string)) error {
 	for _, path := range pathlist {
 		if stat, err := os.Stat(path); err == nil && stat.IsDir() {
-			filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+			err := filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
 				path, _ = filepath.Abs(path)
 
 				if f.IsDir() {
@@ -126,14 +205,19 @@ func findFilesInPaths(pathlist []string, callback func(os.FileInfo, string)) {
 
 				return nil
 			})
+			if err != nil {
+				return err
+			}
 		} else {
 			LoggerInfo.Printf("Path %s does not exists\n", path)
 		}
 	}
+
+	return nil
 }
 
-func findExecutabesInPathes(pathlist []string, callback func(os.FileInfo, string)) {
-	findFilesInPaths(pathlist, func(f os.FileInfo, path string) {
+func findExecutabesInPathes(pathlist []string, callback func(os.FileInfo, string)) error {
+	return findFilesInPaths(pathlist, func(f os.FileInfo, path string) {
 		if f.Mode().IsRegular() && (f.Mode().Perm()&0100 != 0) {
 			callback(f, path)
 		} else {
@@ -142,62 +226,66 @@ func findExecutabesInPathes(pathlist []string, callback func(os.FileInfo, string
 	})
 }
 
-func includePathsForCrontabs(paths []string, username string) []CrontabEntry {
+func includePathsForCrontabs(paths []string, username string) ([]CrontabEntry, error) {
 	var ret []CrontabEntry
-	findFilesInPaths(paths, func(f os.FileInfo, path string) {
-		entries := parseCrontab(path, username)
+	loadErr := &LoadError{}
+
+	loadErr.Add(findFilesInPaths(paths, func(f os.FileInfo, path string) {
+		entries, err := parseCrontab(path, username)
+		loadErr.Add(err)
 		ret = append(ret, entries...)
-	})
-	return ret
+	}))
+	return ret, loadErr.ErrorOrNil()
 }
 
-func includePathForCrontabs(path string, username string) []CrontabEntry {
-	var ret []CrontabEntry
-	var paths []string = []string{path}
-
-	findFilesInPaths(paths, func(f os.FileInfo, path string) {
-		entries := parseCrontab(path, username)
-		ret = append(ret, entries...)
-	})
-	return ret
+func includePathForCrontabs(path string, username string) ([]CrontabEntry, error) {
+	return includePathsForCrontabs([]string{path}, username)
 }
 
-func includeRunPartsDirectories(spec string, paths []string) []CrontabEntry {
+func includeRunPartsDirectories(spec string, paths []string) ([]CrontabEntry, error) {
 	var ret []CrontabEntry
+	loadErr := &LoadError{}
 
 	for _, path := range paths {
-		ret = append(ret, includeRunPartsDirectory(spec, path)...)
+		entries, err := includeRunPartsDirectory(spec, path)
+		loadErr.Add(err)
+		ret = append(ret, entries...)
 	}
 
-	return ret
+	return ret, loadErr.ErrorOrNil()
 }
 
-func includeRunPartsDirectory(spec string, path string) []CrontabEntry {
+// Split optional user prefix of path (format: [user[:group]:]path)
+//
+// The path is separated at the last colon, paths can't contain colons.
+func splitUserPath(value string, defaultUser string) (string, string) {
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		return value[:i], value[i+1:]
+	}
+	return defaultUser, value
+}
+
+func includeRunPartsDirectory(spec string, path string) ([]CrontabEntry, error) {
 	var ret []CrontabEntry
 
-	user := opts.DefaultUser
-
-	// extract user from path
-	if strings.Contains(path, ":") {
-		split := strings.SplitN(path, ":", 2)
-		user, path = split[0], split[1]
-	}
+	user, path := splitUserPath(path, opts.DefaultUser)
 
 	var paths []string = []string{path}
-	findExecutabesInPathes(paths, func(f os.FileInfo, path string) {
-		ret = append(ret, CrontabEntry{Spec: spec, User: user, Command: path})
+	err := findExecutabesInPathes(paths, func(f os.FileInfo, path string) {
+		ret = append(ret, CrontabEntry{Spec: spec, User: user, Command: path, Source: path})
 	})
-	return ret
+	return ret, err
 }
 
-func parseCrontab(path string, username string) []CrontabEntry {
+func parseCrontab(path string, username string) ([]CrontabEntry, error) {
 	var parser *Parser
 	var err error
 
 	file, err := os.Open(path)
 	if err != nil {
-		LoggerError.Fatalf("crontab path: %v err:%v", path, err)
+		return nil, fmt.Errorf("cannot open crontab: %v", err)
 	}
+	defer file.Close()
 
 	if username == CRONTAB_TYPE_SYSTEM {
 		parser, err = NewCronjobSystemParser(file)
@@ -206,41 +294,51 @@ func parseCrontab(path string, username string) []CrontabEntry {
 	}
 
 	if err != nil {
-		LoggerError.Fatalf("Parser read err: %v", err)
+		return nil, fmt.Errorf("cannot read crontab %s: %v", path, err)
 	}
 
 	crontabEntries := parser.Parse()
+	for i := range crontabEntries {
+		crontabEntries[i].Source = path
+	}
 
-	return crontabEntries
+	return crontabEntries, nil
 }
 
-func collectCrontabs(args []string) []CrontabEntry {
+// Collect crontab entries of all crontabs and run-parts directories, returns LoadError
+// with all errors (entries of valid crontabs are returned anyway)
+func collectCrontabs(args []string) ([]CrontabEntry, error) {
 	var ret []CrontabEntry
+	loadErr := &LoadError{}
+
+	include := func(entries []CrontabEntry, err error) {
+		loadErr.Add(err)
+		ret = append(ret, entries...)
+	}
 
 	// include system default crontab
 	if !opts.NoAuto {
-		ret = append(ret, includeSystemDefaults()...)
+		include(includeSystemDefaults())
 	}
 
 	// args: crontab files as normal arguments
 	for _, crontabPath := range args {
-		crontabUser := CRONTAB_TYPE_SYSTEM
+		crontabUser, crontabPath := splitUserPath(crontabPath, CRONTAB_TYPE_SYSTEM)
 
-		if strings.Contains(crontabPath, ":") {
-			split := strings.SplitN(crontabPath, ":", 2)
-			crontabUser, crontabPath = split[0], split[1]
+		crontabAbsPath, f, err := fileGetAbsolutePath(crontabPath)
+		if err != nil {
+			loadErr.Add(err)
+			continue
 		}
 
-		crontabAbsPath, f := fileGetAbsolutePath(crontabPath)
 		if checkIfFileIsValid(f, crontabAbsPath) {
-			entries := parseCrontab(crontabAbsPath, crontabUser)
-			ret = append(ret, entries...)
+			include(parseCrontab(crontabAbsPath, crontabUser))
 		}
 	}
 
 	// --include-crond
 	if len(opts.IncludeCronD) >= 1 {
-		ret = append(ret, includePathsForCrontabs(opts.IncludeCronD, CRONTAB_TYPE_SYSTEM)...)
+		include(includePathsForCrontabs(opts.IncludeCronD, CRONTAB_TYPE_SYSTEM))
 	}
 
 	// --run-parts
@@ -251,7 +349,7 @@ func collectCrontabs(args []string) []CrontabEntry {
 				cronSpec, cronPath := split[0], split[1]
 				cronSpec = fmt.Sprintf("@every %s", cronSpec)
 
-				ret = append(ret, includeRunPartsDirectory(cronSpec, cronPath)...)
+				include(includeRunPartsDirectory(cronSpec, cronPath))
 			} else {
 				LoggerError.Printf("Ignoring --run-parts because of missing time spec: %s\n", runPart)
 			}
@@ -260,39 +358,83 @@ func collectCrontabs(args []string) []CrontabEntry {
 
 	// --run-parts-1min
 	if len(opts.RunParts1m) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("@every 1m", opts.RunParts1m)...)
+		include(includeRunPartsDirectories("@every 1m", opts.RunParts1m))
 	}
 
 	// --run-parts-15min
 	if len(opts.RunParts15m) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("*/15 * * * *", opts.RunParts15m)...)
+		include(includeRunPartsDirectories("*/15 * * * *", opts.RunParts15m))
 	}
 
 	// --run-parts-hourly
 	if len(opts.RunPartsHourly) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("@hourly", opts.RunPartsHourly)...)
+		include(includeRunPartsDirectories("@hourly", opts.RunPartsHourly))
 	}
 
 	// --run-parts-daily
 	if len(opts.RunPartsDaily) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("@daily", opts.RunPartsDaily)...)
+		include(includeRunPartsDirectories("@daily", opts.RunPartsDaily))
 	}
 
 	// --run-parts-weekly
 	if len(opts.RunPartsWeekly) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("@weekly", opts.RunPartsWeekly)...)
+		include(includeRunPartsDirectories("@weekly", opts.RunPartsWeekly))
 	}
 
 	// --run-parts-monthly
 	if len(opts.RunPartsMonthly) >= 1 {
-		ret = append(ret, includeRunPartsDirectories("@monthly", opts.RunPartsMonthly)...)
+		include(includeRunPartsDirectories("@monthly", opts.RunPartsMonthly))
 	}
 
-	return ret
+	return ret, loadErr.ErrorOrNil()
 }
 
-func includeSystemDefaults() []CrontabEntry {
+// Return absolute paths of crontab files, include and run-parts directories (--watch)
+func crontabWatchPaths(args []string) []string {
+	var paths []string
+
+	// system defaults of all distributions
+	if !opts.NoAuto {
+		paths = append(paths, "/etc/crontab", "/etc/crontabs", "/etc/cron.d")
+	}
+
+	// crontab files, include directories and run-parts directories (with optional user)
+	for _, path := range args {
+		_, path = splitUserPath(path, "")
+		paths = append(paths, path)
+	}
+
+	paths = append(paths, opts.IncludeCronD...)
+
+	for _, runPart := range opts.RunParts {
+		_, path := splitUserPath(runPart, "")
+		paths = append(paths, path)
+	}
+
+	for _, runParts := range [][]string{opts.RunParts1m, opts.RunParts15m, opts.RunPartsHourly, opts.RunPartsDaily, opts.RunPartsWeekly, opts.RunPartsMonthly} {
+		for _, path := range runParts {
+			_, path = splitUserPath(path, "")
+			paths = append(paths, path)
+		}
+	}
+
+	for i, path := range paths {
+		if absPath, err := filepath.Abs(path); err == nil {
+			paths[i] = absPath
+		}
+	}
+
+	return paths
+}
+
+func includeSystemDefaults() ([]CrontabEntry, error) {
 	var ret []CrontabEntry
+	loadErr := &LoadError{}
+
+	include := func(entries []CrontabEntry, err error) {
+		loadErr.Add(err)
+		ret = append(ret, entries...)
+	}
 
 	systemDetected := false
 
@@ -303,7 +445,7 @@ func includeSystemDefaults() []CrontabEntry {
 		LoggerInfo.Println(" --> detected Alpine family, using distribution defaults")
 
 		if checkIfDirectoryExists("/etc/crontabs") {
-			ret = append(ret, includePathForCrontabs("/etc/crontabs", opts.DefaultUser)...)
+			include(includePathForCrontabs("/etc/crontabs", opts.DefaultUser))
 		}
 
 		systemDetected = true
@@ -316,7 +458,7 @@ func includeSystemDefaults() []CrontabEntry {
 		LoggerInfo.Println(" --> detected RedHat family, using distribution defaults")
 
 		if checkIfFileExists("/etc/crontabs") {
-			ret = append(ret, includePathForCrontabs("/etc/crontabs", CRONTAB_TYPE_SYSTEM)...)
+			include(includePathForCrontabs("/etc/crontabs", CRONTAB_TYPE_SYSTEM))
 		}
 
 		systemDetected = true
@@ -329,7 +471,7 @@ func includeSystemDefaults() []CrontabEntry {
 		LoggerInfo.Println(" --> detected SuSE family, using distribution defaults")
 
 		if checkIfFileExists("/etc/crontab") {
-			ret = append(ret, includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM)...)
+			include(includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM))
 		}
 
 		systemDetected = true
@@ -342,7 +484,7 @@ func includeSystemDefaults() []CrontabEntry {
 		LoggerInfo.Println(" --> detected Debian family, using distribution defaults")
 
 		if checkIfFileExists("/etc/crontab") {
-			ret = append(ret, includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM)...)
+			include(includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM))
 		}
 
 		systemDetected = true
@@ -353,24 +495,39 @@ func includeSystemDefaults() []CrontabEntry {
 	// ----------------------
 	if !systemDetected {
 		if checkIfFileExists("/etc/crontab") {
-			ret = append(ret, includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM)...)
+			include(includePathForCrontabs("/etc/crontab", CRONTAB_TYPE_SYSTEM))
 		}
 
 		if checkIfFileExists("/etc/crontabs") {
-			ret = append(ret, includePathForCrontabs("/etc/crontabs", CRONTAB_TYPE_SYSTEM)...)
+			include(includePathForCrontabs("/etc/crontabs", CRONTAB_TYPE_SYSTEM))
 		}
 	}
 
 	if checkIfDirectoryExists("/etc/cron.d") {
-		ret = append(ret, includePathForCrontabs("/etc/cron.d", CRONTAB_TYPE_SYSTEM)...)
+		include(includePathForCrontabs("/etc/cron.d", CRONTAB_TYPE_SYSTEM))
 	}
 
-	return ret
+	return ret, loadErr.ErrorOrNil()
 }
 
 func main() {
+	// apply process attributes and exec cronjob command (see procAttrWrap)
+	if len(os.Args) >= 2 && os.Args[1] == PROCATTR_EXEC_COMMAND {
+		procAttrExec(os.Args[2:])
+	}
+
+	// control client of running daemon (see ctlMain)
+	if len(os.Args) >= 2 && os.Args[1] == CTL_COMMAND {
+		os.Exit(ctlMain(os.Args[2:]))
+	}
+
 	initLogger()
 	args := initArgParser()
+	setupLogger()
+
+	if err := redactor.Init(opts.Redact, opts.RedactEnv); err != nil {
+		logFatalErrorAndExit(err, 1)
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP)
@@ -390,32 +547,85 @@ func main() {
 		}
 	}
 
+	initCgroup()
+
 	// get current path
 	confDir, err := os.Getwd()
 	if err != nil {
 		LoggerError.Fatalf("Could not get current path: %v", err)
 	}
 
+	eventLog.SetSize(opts.EventBuffer)
 	runner := NewRunner()
 
-	LoggerInfo.Printf("Starting metrics %s%s", opts.ListenAddress, opts.MetricsPath)
-
 	exporter := NewMetricsExporter(runner)
 	prometheus.MustRegister(exporter)
 
-	http.Handle(opts.MetricsPath, promhttp.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		tmpl := template.New(`cronjobs`)
-		tmpl, _ = tmpl.Parse(CRONLIST)
+	// web interface, api and telemetry can share listeners
+	listeners := map[string]*http.ServeMux{}
+	listenerMux := func(address string) *http.ServeMux {
+		address = componentListenAddress(address)
+		if address == LISTEN_OFF {
+			return nil
+		}
+		if listeners[address] == nil {
+			listeners[address] = http.NewServeMux()
+		}
+		return listeners[address]
+	}
+
+	// telemetry
+	if mux := listenerMux(opts.TelemetryAddress); mux != nil {
+		LoggerInfo.Printf("Starting metrics %s%s", componentListenAddress(opts.TelemetryAddress), opts.MetricsPath)
+		mux.Handle(opts.MetricsPath, promhttp.Handler())
+	}
+
+	// json api
+	apiHandler := NewApiHandler(runner)
+	apiMux := listenerMux(opts.ApiAddress)
+	if apiMux != nil {
+		apiMux.Handle(API_PREFIX, apiHandler)
+		apiMux.HandleFunc("/api/events", apiHandler.events)
+	}
+
+	// web interface
+	uiHandler, err := NewUiHandler(runner)
+	if err != nil {
+		logFatalErrorAndExit(err, 1)
+	}
+	if mux := listenerMux(opts.UiAddress); mux != nil {
+		mux.Handle("/", uiHandler)
+		mux.Handle("/static/", uiStaticHandler())
 
-		params := tplCronListParams{
-			Cronjobs: runner.GetJobs(),
+		// job logs
+		if opts.JobLogDir != "" {
+			mux.Handle("/logs/", jobLogHandler())
 		}
+	}
 
-		tmpl.Execute(w, params)
-	})
+	if err := loadHttpSecurity(); err != nil {
+		logFatalErrorAndExit(err, 1)
+	}
 
-	go http.ListenAndServe(opts.ListenAddress, nil)
+	for address, mux := range listeners {
+		go listenAndServe(address, probeHandler(httpAuth.Middleware(mux)))
+	}
+
+	// --watch (paths are relative to initial directory)
+	if opts.Watch {
+		watcher, err := NewCrontabWatcher(crontabWatchPaths(args), opts.WatchDebounce)
+		if err != nil {
+			logFatalErrorAndExit(err, 1)
+		}
+		go watcher.Run()
+	}
+
+	// reply channel of reload requested by api or --watch
+	var reloadReply chan ReloadResult
+
+	// crontab entries of scheduled jobs
+	var activeEntries []CrontabEntry
+	started := false
 
 	// endless daemon-reload loop
 	for {
@@ -425,34 +635,89 @@ func main() {
 			LoggerError.Fatalf("Cannot switch to path %s: %v", confDir, err)
 		}
 
-		// create new cron runner
-		runner.CreateCronjobs(collectCrontabs(args))
-		registerRunnerShutdown(runner)
-		registerRunnerChildShutdown(runner)
+		// resolve users and groups again
+		identityCache.Expire()
+
+		// build and validate new jobs, previous jobs keep running until they are replaced
+		loadErrors := &LoadError{}
+		crontabEntries, collectErr := collectCrontabs(args)
+		loadErrors.Add(collectErr)
+
+		// secrets of previous and new crontabs are masked in load errors
+		redactor.SetCrontabs(append(append([]CrontabEntry{}, activeEntries...), crontabEntries...))
+		jobSet, jobsErr := runner.CreateCronjobs(crontabEntries)
+		loadErrors.Add(jobsErr)
+		loadErr := loadErrors.ErrorOrNil()
 
 		// chdir to root to prevent relative path errors
-		os.Chdir("/")
-		if err != nil {
-			LoggerError.Fatalf("Cannot switch to path %s: %v", confDir, err)
+		if err := os.Chdir("/"); err != nil {
+			LoggerError.Fatalf("Cannot switch to path /: %v", err)
 		}
 
-		// start new cron runner
-		runner.Start()
+		// swap jobs if everything was loaded (valid jobs are started on first load)
+		previousJobs := runner.GetJobs()
+		applied := loadErr == nil || !started
+		if applied {
+			runner.Swap(jobSet)
+			activeEntries = crontabEntries
+			redactor.SetCrontabs(activeEntries)
+
+			// runner is kept across reloads, register signal handlers only once
+			if !started {
+				registerRunnerShutdown(runner)
+				registerRunnerChildShutdown(runner)
+			}
+			started = true
+		}
 
-		// check if we received SIGHUP and start a new loop
-		s := <-c
-		LoggerInfo.Println("Got signal: ", s)
-		runner.Stop()
-		LoggerInfo.Println("Reloading configuration")
+		reloadResult := newReloadResult(previousJobs, jobSet.jobs, applied, loadErr)
+		daemonStatus.Reloaded(activeEntries, loadErr)
+		publishReloadEvent(reloadResult.Jobs, loadErr)
+		if applied {
+			LoggerInfo.Printf("Loaded %d cron jobs (%d added, %d removed, %d unchanged)", reloadResult.Jobs, len(reloadResult.Added), len(reloadResult.Removed), reloadResult.Unchanged)
+		} else {
+			LoggerError.Printf("Reload failed, keeping %d previous cron jobs: %v", reloadResult.Jobs, loadErr)
+		}
+
+		if reloadReply != nil {
+			reloadReply <- reloadResult
+			reloadReply = nil
+		}
+
+		// check if we received SIGHUP or a reload request and start a new loop
+		select {
+		case s := <-c:
+			LoggerInfo.Signal(s)
+		case request := <-reloadRequests:
+			LoggerInfo.Printf("Reload requested by %s", request.reason)
+			reloadReply = request.reply
+		}
+		LoggerInfo.Reload()
+
+		// reload certificates and credentials, previous ones are kept on errors
+		if err := loadHttpSecurity(); err != nil {
+			LoggerError.Printf("Cannot reload tls or auth files: %v", err)
+		}
 	}
 }
 
+// Load tls certificate (--tls-*) and auth files (--auth-*)
+func loadHttpSecurity() error {
+	if opts.TlsCert != "" {
+		if err := httpTls.Load(opts.TlsCert, opts.TlsKey, opts.TlsClientCA); err != nil {
+			return err
+		}
+	}
+
+	return httpAuth.Load(opts.AuthFile, opts.AuthTokenFile)
+}
+
 func registerRunnerShutdown(runner *Runner) {
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		s := <-c
-		LoggerInfo.Println("Got signal: ", s)
+		LoggerInfo.Signal(s)
 		runner.Stop()
 
 		LoggerInfo.Println("Terminated")
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitUserPath(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		user  string
+		path  string
+	}{
+		{"/etc/crontab", "default", "/etc/crontab"},
+		{"www-data:/etc/crontab", "www-data", "/etc/crontab"},
+		{"1000:/etc/crontab", "1000", "/etc/crontab"},
+		{"www-data:adm:/etc/crontab", "www-data:adm", "/etc/crontab"},
+		{":adm:/etc/crontab", ":adm", "/etc/crontab"},
+		{"1000:1000:/etc/cron.hourly", "1000:1000", "/etc/cron.hourly"},
+		// paths are separated at the last colon (paths can't contain colons)
+		{"root:/srv/a:b/crontab", "root:/srv/a", "b/crontab"},
+	} {
+		user, path := splitUserPath(test.value, "default")
+		if user != test.user || path != test.path {
+			t.Errorf("splitUserPath(%q): expected %q %q, got %q %q", test.value, test.user, test.path, user, path)
+		}
+	}
+}
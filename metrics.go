@@ -11,6 +11,11 @@ type MetricsExporter struct {
 	r               *Runner
 	CronJobStatus   *prometheus.Desc
 	CronJobDuration *prometheus.Desc
+	CronJobOOMKill  *prometheus.Desc
+	UserLookupError *prometheus.Desc
+	ReloadCount     *prometheus.Desc
+	ReloadTime      *prometheus.Desc
+	ReloadSuccess   *prometheus.Desc
 }
 
 func NewMetricsExporter(r *Runner) *MetricsExporter {
@@ -26,12 +31,42 @@ func NewMetricsExporter(r *Runner) *MetricsExporter {
 			[]string{"jobname", "id"},
 			nil,
 		),
+		CronJobOOMKill: prometheus.NewDesc("cronjob_execute_oom_killed",
+			"Last cronjob run was killed by the OOM killer",
+			[]string{"jobname", "id"},
+			nil,
+		),
+		UserLookupError: prometheus.NewDesc("cronjob_user_lookup_errors",
+			"Number of failed user and group lookups",
+			[]string{"user"},
+			nil,
+		),
+		ReloadCount: prometheus.NewDesc("cronjob_reload_total",
+			"Number of crontab loads (initial load and reloads)",
+			nil,
+			nil,
+		),
+		ReloadTime: prometheus.NewDesc("cronjob_reload_last_timestamp_seconds",
+			"Time of last crontab load",
+			nil,
+			nil,
+		),
+		ReloadSuccess: prometheus.NewDesc("cronjob_reload_last_success",
+			"Last crontab load was successful",
+			nil,
+			nil,
+		),
 	}
 }
 
 func (collector *MetricsExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collector.CronJobStatus
 	ch <- collector.CronJobDuration
+	ch <- collector.CronJobOOMKill
+	ch <- collector.UserLookupError
+	ch <- collector.ReloadCount
+	ch <- collector.ReloadTime
+	ch <- collector.ReloadSuccess
 }
 
 func (collector *MetricsExporter) Collect(ch chan<- prometheus.Metric) {
@@ -45,6 +80,28 @@ func (collector *MetricsExporter) Collect(ch chan<- prometheus.Metric) {
 				ch <- prometheus.MustNewConstMetric(collector.CronJobStatus, prometheus.CounterValue, 0, e.Name, fmt.Sprintf("%d", e.Id))
 			}
 			ch <- prometheus.MustNewConstMetric(collector.CronJobDuration, prometheus.CounterValue, float64(e.Elapsed/time.Second), e.Name, fmt.Sprintf("%d", e.Id))
+			if e.OOMKilled {
+				ch <- prometheus.MustNewConstMetric(collector.CronJobOOMKill, prometheus.GaugeValue, 1, e.Name, fmt.Sprintf("%d", e.Id))
+			} else {
+				ch <- prometheus.MustNewConstMetric(collector.CronJobOOMKill, prometheus.GaugeValue, 0, e.Name, fmt.Sprintf("%d", e.Id))
+			}
+		}
+	}
+
+	for user, count := range identityCache.Errors() {
+		ch <- prometheus.MustNewConstMetric(collector.UserLookupError, prometheus.CounterValue, float64(count), user)
+	}
+
+	daemonStatus.mu.RLock()
+	defer daemonStatus.mu.RUnlock()
+
+	if daemonStatus.ReloadCount > 0 {
+		ch <- prometheus.MustNewConstMetric(collector.ReloadCount, prometheus.CounterValue, float64(daemonStatus.ReloadCount))
+		ch <- prometheus.MustNewConstMetric(collector.ReloadTime, prometheus.GaugeValue, float64(daemonStatus.LastReload.Unix()))
+		if daemonStatus.LastReloadError != nil {
+			ch <- prometheus.MustNewConstMetric(collector.ReloadSuccess, prometheus.GaugeValue, 0)
+		} else {
+			ch <- prometheus.MustNewConstMetric(collector.ReloadSuccess, prometheus.GaugeValue, 1)
 		}
 	}
 }
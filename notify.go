@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	NOTIFY_EVENT_FAILURE  = "failure"
+	NOTIFY_EVENT_TIMEOUT  = "timeout"
+	NOTIFY_EVENT_RECOVERY = "recovery"
+	NOTIFY_EVENT_SUCCESS  = "success"
+
+	NOTIFY_HTTP_TIMEOUT = 10 * time.Second
+)
+
+var (
+	notifyHttpClient = &http.Client{Timeout: NOTIFY_HTTP_TIMEOUT}
+
+	// parsed --notify-template
+	notifyDefaultTemplate *template.Template
+
+	notifyTemplateFuncs = template.FuncMap{
+		// encode value as json (eg. for embedding output into json bodies)
+		"json": func(value interface{}) (string, error) {
+			ret, err := json.Marshal(value)
+			return string(ret), err
+		},
+	}
+)
+
+// Notification settings of cronjob, global options are used as defaults
+type NotifyConfig struct {
+	Webhook  string
+	Events   []string
+	Template string
+
+	// parsed template, set by Load
+	tmpl *template.Template
+}
+
+// Payload of notification (json body and template data)
+type Notification struct {
+	Event    string          `json:"event"`
+	Job      NotificationJob `json:"job"`
+	RunId    uint64          `json:"run_id"`
+	ExitCode int             `json:"exit_code"`
+	Error    string          `json:"error,omitempty"`
+	Duration float64         `json:"duration"`
+	Output   string          `json:"output"`
+	Hostname string          `json:"hostname"`
+	Time     time.Time       `json:"time"`
+}
+
+type NotificationJob struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	User    string `json:"user"`
+	Spec    string `json:"spec"`
+	Command string `json:"command"`
+}
+
+// Validate webhook and events and parse template (once when the crontab is loaded)
+func (config *NotifyConfig) Load() error {
+	if err := notifyWebhookValidate(config.Webhook); err != nil {
+		return err
+	}
+
+	for _, event := range config.Events {
+		switch event {
+		case NOTIFY_EVENT_FAILURE, NOTIFY_EVENT_TIMEOUT, NOTIFY_EVENT_RECOVERY, NOTIFY_EVENT_SUCCESS:
+		default:
+			return fmt.Errorf("invalid notification event %q", event)
+		}
+	}
+
+	if config.Template != "" {
+		tmpl, err := loadNotifyTemplate(config.Template)
+		if err != nil {
+			return err
+		}
+		config.tmpl = tmpl
+	}
+
+	return nil
+}
+
+// Check if webhook is a valid http(s) url
+func notifyWebhookValidate(webhook string) error {
+	if webhook == "" {
+		return nil
+	}
+
+	if err := httpUrlValidate(webhook); err != nil {
+		return fmt.Errorf("invalid notification webhook %q: %v", webhook, err)
+	}
+
+	return nil
+}
+
+func (config NotifyConfig) webhook() string {
+	if config.Webhook != "" {
+		return config.Webhook
+	}
+	return opts.NotifyWebhook
+}
+
+func (config NotifyConfig) template() *template.Template {
+	if config.tmpl != nil {
+		return config.tmpl
+	}
+	return notifyDefaultTemplate
+}
+
+func (config NotifyConfig) enabled(event string) bool {
+	events := config.Events
+	if len(events) == 0 {
+		events = opts.NotifyOn
+	}
+
+	for _, enabledEvent := range events {
+		if enabledEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+func loadNotifyTemplate(path string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read notification template: %v", err)
+	}
+
+	tmpl, err := template.New(path).Funcs(notifyTemplateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+// Return event of cronjob run for notifications (empty if nothing should be sent)
+func notifyEvent(config NotifyConfig, err error, timedOut bool, previousFailed bool) string {
+	var event string
+
+	switch {
+	case timedOut:
+		event = NOTIFY_EVENT_TIMEOUT
+	case err != nil:
+		event = NOTIFY_EVENT_FAILURE
+	case previousFailed && config.enabled(NOTIFY_EVENT_RECOVERY):
+		event = NOTIFY_EVENT_RECOVERY
+	default:
+		event = NOTIFY_EVENT_SUCCESS
+	}
+
+	if !config.enabled(event) {
+		return ""
+	}
+
+	return event
+}
+
+// Send notification for finished cronjob run (in background)
+func notifyCronjobResult(id int, runId uint64, cronjob CrontabEntry, output string, err error, elapsed time.Duration, timedOut bool, previousFailed bool) {
+	webhook := cronjob.Notify.webhook()
+	if webhook == "" {
+		return
+	}
+
+	event := notifyEvent(cronjob.Notify, err, timedOut, previousFailed)
+	if event == "" {
+		return
+	}
+
+	notification := Notification{
+		Event: event,
+		Job: NotificationJob{
+			Id:      id,
+			Name:    redactor.Redact(cronjob.Name),
+			User:    cronjob.User,
+			Spec:    cronjob.Spec,
+			Command: redactor.Redact(cronjob.Command),
+		},
+		RunId:    runId,
+		ExitCode: exitCode(err),
+		Duration: elapsed.Seconds(),
+		Output:   redactor.Redact(outputTail(output, opts.NotifyOutputLines)),
+		Time:     time.Now(),
+	}
+	notification.Hostname, _ = os.Hostname()
+	if err != nil {
+		notification.Error = redactor.Redact(err.Error())
+	}
+
+	go func() {
+		body, contentType, err := notification.Body(cronjob.Notify.template())
+		if err != nil {
+			LoggerError.Printf("Cannot create notification for cron job %v: %v", LoggerError.CronjobToString(cronjob), err)
+			return
+		}
+
+		if err := httpPostWithRetry(webhook, contentType, body, opts.NotifyRetries); err != nil {
+			LoggerError.Printf("Cannot send %s notification for cron job %v: %v", event, LoggerError.CronjobToString(cronjob), err)
+		}
+	}()
+}
+
+// Render body of notification (json payload or template)
+func (notification Notification) Body(tmpl *template.Template) ([]byte, string, error) {
+	if tmpl == nil {
+		body, err := json.Marshal(notification)
+		return body, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), opts.NotifyContentType, nil
+}
+
+// Return last lines of output
+func outputTail(output string, lines int) string {
+	if lines <= 0 {
+		return output
+	}
+
+	split := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(split) > lines {
+		split = split[len(split)-lines:]
+	}
+
+	return strings.Join(split, "\n")
+}
+
+// Send http post request, retries with exponential backoff on errors and 5xx responses
+func httpPostWithRetry(url string, contentType string, body []byte, retries int) error {
+	var err error
+	backoff := time.Second
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = notifyHttpClient.Post(url, contentType, bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+
+		err = fmt.Errorf("unexpected status %s", resp.Status)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			// client errors won't be fixed by retrying
+			return err
+		}
+	}
+
+	return err
+}
@@ -9,7 +9,7 @@ import (
 )
 
 const (
-	ENV_LINE = `^(\S+)=(\S+)\s*$`
+	ENV_LINE = `^(\S+)=(\S+|"[^"]*"|'[^']*')\s*$`
 
 	//                     ----spec------------------------------------    --user--  -cmd-
 	CRONJOB_SYSTEM = `^\s*([^@\s]+\s+\S+\s+\S+\s+\S+\s+\S+|@every\s+\S+)\s+([^\s]+)\s+(.+)$`
@@ -35,13 +35,22 @@ type EnvVar struct {
 }
 
 type CrontabEntry struct {
-	Name    string
-	Spec    string
-	User    string
-	Command string
-	Pwd     string
-	Env     []string
-	Shell   string
+	Name      string
+	Spec      string
+	User      string
+	Command   string
+	Pwd       string
+	Env       []string
+	Shell     string
+	Cgroup    CgroupLimits
+	ProcAttr  ProcAttr
+	EnvPolicy EnvPolicy
+	Timeout   string
+	Notify    NotifyConfig
+	Mail      MailConfig
+	PingUrl   string
+	Source    string
+	Tags      []string
 }
 
 type Parser struct {
@@ -89,7 +98,15 @@ func (p *Parser) parseLines() []CrontabEntry {
 	var environment []string
 
 	shell := DEFAULT_SHELL
-	pwd := "/"
+	pwd := ""
+	cgroupLimits := CgroupLimits{}
+	procAttr := ProcAttr{}
+	envPolicy := EnvPolicy{}
+	timeout := ""
+	notify := NotifyConfig{}
+	mail := MailConfig{}
+	pingUrl := ""
+	var tags []string
 
 	specCleanupRegexp := regexp.MustCompile(`\s+`)
 
@@ -106,13 +123,50 @@ func (p *Parser) parseLines() []CrontabEntry {
 		if envLineRegex.MatchString(line) == true {
 			m := envLineRegex.FindStringSubmatch(line)
 			envName := strings.TrimSpace(m[1])
-			envValue := strings.TrimSpace(m[2])
+			envValue := envValueUnquote(strings.TrimSpace(m[2]))
 
 			if envName == "SHELL" {
 				// custom shell for command
 				shell = envValue
 			} else if envName == "PWD" {
 				pwd = envValue
+			} else if envName == "CROND_CGROUP_MEMORY_MAX" {
+				cgroupLimits.MemoryMax = envValue
+			} else if envName == "CROND_CGROUP_CPU_MAX" {
+				cgroupLimits.CpuMax = envValue
+			} else if envName == "CROND_CGROUP_PIDS_MAX" {
+				cgroupLimits.PidsMax = envValue
+			} else if envName == "CROND_NICE" {
+				procAttr.Nice = envValue
+			} else if envName == "CROND_IONICE" {
+				procAttr.IONice = envValue
+			} else if envName == "CROND_RLIMIT_NOFILE" {
+				procAttr.RlimitNofile = envValue
+			} else if envName == "CROND_RLIMIT_AS" {
+				procAttr.RlimitAs = envValue
+			} else if envName == "CROND_ENV_POLICY" {
+				envPolicy.Policy = envValue
+			} else if envName == "CROND_ENV_ALLOW" {
+				envPolicy.Allow = splitList(envValue)
+			} else if envName == "CROND_ENV_DENY" {
+				envPolicy.Deny = splitList(envValue)
+			} else if envName == "CROND_TIMEOUT" {
+				timeout = envValue
+			} else if envName == "CROND_NOTIFY_WEBHOOK" {
+				notify.Webhook = envValue
+			} else if envName == "CROND_NOTIFY_ON" {
+				notify.Events = splitList(envValue)
+			} else if envName == "CROND_NOTIFY_TEMPLATE" {
+				notify.Template = envValue
+			} else if envName == "MAILTO" {
+				mail.To = envValue
+				mail.ToSet = true
+			} else if envName == "MAILFROM" {
+				mail.From = envValue
+			} else if envName == "CROND_PING_URL" {
+				pingUrl = envValue
+			} else if envName == "CROND_TAGS" {
+				tags = splitList(envValue)
 			} else {
 				// normal environment variable
 				environment = append(environment, fmt.Sprintf("%s=%s", envName, envValue))
@@ -144,9 +198,21 @@ func (p *Parser) parseLines() []CrontabEntry {
 			crontabSpec = specCleanupRegexp.ReplaceAllString(crontabSpec, " ")
 
 			entries = append(entries, CrontabEntry{Name: cronjobName, Spec: crontabSpec, User: crontabUser,
-				Command: crontabCommand, Pwd: pwd, Env: environment, Shell: shell})
+				Command: crontabCommand, Pwd: pwd, Env: environment, Shell: shell, Cgroup: cgroupLimits, ProcAttr: procAttr, EnvPolicy: envPolicy,
+				Timeout: timeout, Notify: notify, Mail: mail, PingUrl: pingUrl, Tags: tags})
 		}
 	}
 
 	return entries
 }
+
+// Remove surrounding quotes from environment value
+func envValueUnquote(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+
+	return value
+}
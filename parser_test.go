@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEnvValueUnquote(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected string
+	}{
+		{`value`, `value`},
+		{`"quoted value"`, `quoted value`},
+		{`'quoted value'`, `quoted value`},
+		{`""`, ``},
+		{`"`, `"`},
+		{`"mixed'`, `"mixed'`},
+		{`"inner" "quotes"`, `inner" "quotes`},
+		{`a"b"`, `a"b"`},
+	} {
+		if unquoted := envValueUnquote(test.value); unquoted != test.expected {
+			t.Errorf("envValueUnquote(%q): expected %q, got %q", test.value, test.expected, unquoted)
+		}
+	}
+}
+
+func TestParserDirectives(t *testing.T) {
+	crontab := strings.Join([]string{
+		`# comment`,
+		`PATH=/usr/bin`,
+		`GREETING="hello world"`,
+		`CROND_CGROUP_MEMORY_MAX=512M`,
+		`CROND_CGROUP_CPU_MAX="50000 100000"`,
+		`CROND_CGROUP_PIDS_MAX=64`,
+		`CROND_NICE=10`,
+		`CROND_IONICE=best-effort:7`,
+		`CROND_RLIMIT_NOFILE=1024:4096`,
+		`CROND_RLIMIT_AS=2G`,
+		`CROND_ENV_POLICY=clean`,
+		`CROND_ENV_ALLOW="LANG, LC_*"`,
+		`CROND_ENV_DENY='*_PASSWORD'`,
+		`CROND_TIMEOUT=1h`,
+		`CROND_NOTIFY_WEBHOOK=https://hooks.example.com/cron`,
+		`CROND_NOTIFY_ON=failure,recovery`,
+		`CROND_NOTIFY_TEMPLATE='{{.Job}} failed'`,
+		`CROND_PING_URL=https://hc.example.com/ping/abc`,
+		`CROND_TAGS="backup db"`,
+		`MAILTO=ops@example.com`,
+		`MAILFROM=cron@example.com`,
+		`SHELL=/bin/bash`,
+		`PWD=/srv`,
+		`0  3 * *   * backup /usr/local/bin/backup.sh --all`,
+	}, "\n")
+
+	parser, _ := NewCronjobSystemParser(strings.NewReader(crontab))
+	entries := parser.Parse()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+
+	for _, test := range []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"Spec", entry.Spec, "0 3 * * *"},
+		{"User", entry.User, "backup"},
+		{"Command", entry.Command, "/usr/local/bin/backup.sh --all"},
+		{"Name", entry.Name, "backup.sh"},
+		{"Shell", entry.Shell, "/bin/bash"},
+		{"Pwd", entry.Pwd, "/srv"},
+		{"Env", entry.Env, []string{"PATH=/usr/bin", "GREETING=hello world"}},
+		{"Cgroup", entry.Cgroup, CgroupLimits{MemoryMax: "512M", CpuMax: "50000 100000", PidsMax: "64"}},
+		{"ProcAttr", entry.ProcAttr, ProcAttr{Nice: "10", IONice: "best-effort:7", RlimitNofile: "1024:4096", RlimitAs: "2G"}},
+		{"EnvPolicy", entry.EnvPolicy, EnvPolicy{Policy: "clean", Allow: []string{"LANG", "LC_*"}, Deny: []string{"*_PASSWORD"}}},
+		{"Timeout", entry.Timeout, "1h"},
+		{"Notify.Webhook", entry.Notify.Webhook, "https://hooks.example.com/cron"},
+		{"Notify.Events", entry.Notify.Events, []string{"failure", "recovery"}},
+		{"Notify.Template", entry.Notify.Template, "{{.Job}} failed"},
+		{"Mail", entry.Mail, MailConfig{To: "ops@example.com", ToSet: true, From: "cron@example.com"}},
+		{"PingUrl", entry.PingUrl, "https://hc.example.com/ping/abc"},
+		{"Tags", entry.Tags, []string{"backup", "db"}},
+	} {
+		if fmt.Sprintf("%#v", test.value) != fmt.Sprintf("%#v", test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.expected, test.value)
+		}
+	}
+}
+
+func TestParserUserCrontab(t *testing.T) {
+	crontab := strings.Join([]string{
+		`CROND_NICE=5`,
+		`*/5 * * * * /usr/bin/first`,
+		`CROND_NICE=-5`,
+		`@every 1h /usr/bin/second`,
+		`invalid line`,
+	}, "\n")
+
+	parser, _ := NewCronjobUserParser(strings.NewReader(crontab), "www-data")
+	entries := parser.Parse()
+
+	for _, entry := range entries {
+		if entry.User != "www-data" {
+			t.Errorf("expected user www-data, got %q", entry.User)
+		}
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	// directives apply to following cronjobs
+	for i, test := range []struct {
+		spec    string
+		command string
+		nice    string
+	}{
+		{"*/5 * * * *", "/usr/bin/first", "5"},
+		{"@every 1h", "/usr/bin/second", "-5"},
+	} {
+		if entries[i].Spec != test.spec || entries[i].Command != test.command || entries[i].ProcAttr.Nice != test.nice {
+			t.Errorf("entry %d: expected %q %q nice %s, got %q %q nice %s", i, test.spec, test.command, test.nice, entries[i].Spec, entries[i].Command, entries[i].ProcAttr.Nice)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	PING_CONTENT_TYPE = "text/plain; charset=utf-8"
+)
+
+// Check if ping url (CROND_PING_URL) is a valid http(s) url
+func pingUrlValidate(pingUrl string) error {
+	if pingUrl == "" {
+		return nil
+	}
+
+	if err := httpUrlValidate(pingUrl); err != nil {
+		return fmt.Errorf("invalid ping url %q: %v", pingUrl, err)
+	}
+
+	return nil
+}
+
+// Return ping url with suffix (eg. /start, /fail)
+func pingUrlWithSuffix(pingUrl string, suffix string) string {
+	if suffix == "" {
+		return pingUrl
+	}
+
+	parsed, err := url.Parse(pingUrl)
+	if err != nil {
+		return pingUrl
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + suffix
+
+	return parsed.String()
+}
+
+// Send start ping of cronjob (in background), returned channel is closed after ping was sent
+func pingCronjobStart(cronjob CrontabEntry) chan struct{} {
+	done := make(chan struct{})
+	if cronjob.PingUrl == "" {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		pingSend(cronjob, "/start", "")
+	}()
+
+	return done
+}
+
+// Send success or fail ping of cronjob (in background) after start ping was sent
+func pingCronjobResult(cronjob CrontabEntry, started chan struct{}, output string, err error, elapsed time.Duration) {
+	if cronjob.PingUrl == "" {
+		return
+	}
+
+	suffix := ""
+	body := fmt.Sprintf("duration: %.3fs\n", elapsed.Seconds())
+	if err != nil {
+		suffix = "/fail"
+		body = fmt.Sprintf("exit code: %d\nerror: %v\n%s\n%s", exitCode(err), err, body, outputTail(output, opts.PingOutputLines))
+	}
+	body = redactor.Redact(body)
+
+	go func() {
+		// keep order of pings, start ping must arrive first
+		<-started
+		pingSend(cronjob, suffix, body)
+	}()
+}
+
+func pingSend(cronjob CrontabEntry, suffix string, body string) {
+	pingUrl := pingUrlWithSuffix(cronjob.PingUrl, suffix)
+
+	if err := httpPostWithRetry(pingUrl, PING_CONTENT_TYPE, []byte(body), opts.PingRetries); err != nil {
+		LoggerError.Printf("Cannot send ping for cron job %v: %v", LoggerError.CronjobToString(cronjob), redactor.Redact(err.Error()))
+	}
+}
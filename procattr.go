@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// internal command for applying process attributes before executing the cronjob
+	PROCATTR_EXEC_COMMAND = "procattr-exec"
+
+	IOPRIO_CLASS_REALTIME    = 1
+	IOPRIO_CLASS_BEST_EFFORT = 2
+	IOPRIO_CLASS_IDLE        = 3
+)
+
+// Process attributes of a cronjob (nice, ionice and resource limits)
+type ProcAttr struct {
+	Nice         string
+	IONice       string
+	RlimitNofile string
+	RlimitAs     string
+}
+
+// Check if no process attribute is set
+func (attr ProcAttr) IsEmpty() bool {
+	return attr.Nice == "" && attr.IONice == "" && attr.RlimitNofile == "" && attr.RlimitAs == ""
+}
+
+func (attr ProcAttr) String() string {
+	parts := []string{}
+
+	if attr.Nice != "" {
+		parts = append(parts, fmt.Sprintf("nice:%v", attr.Nice))
+	}
+
+	if attr.IONice != "" {
+		parts = append(parts, fmt.Sprintf("ionice:%v", attr.IONice))
+	}
+
+	if attr.RlimitNofile != "" {
+		parts = append(parts, fmt.Sprintf("nofile:%v", attr.RlimitNofile))
+	}
+
+	if attr.RlimitAs != "" {
+		parts = append(parts, fmt.Sprintf("as:%v", attr.RlimitAs))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Check if all process attributes can be applied
+func (attr ProcAttr) Validate() error {
+	if attr.Nice != "" {
+		if _, err := parseNice(attr.Nice); err != nil {
+			return err
+		}
+	}
+
+	if attr.IONice != "" {
+		if _, _, err := parseIONice(attr.IONice); err != nil {
+			return err
+		}
+	}
+
+	if attr.RlimitNofile != "" {
+		if _, err := parseRlimit(attr.RlimitNofile); err != nil {
+			return err
+		}
+	}
+
+	if attr.RlimitAs != "" {
+		if _, err := parseRlimit(attr.RlimitAs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Arguments for PROCATTR_EXEC_COMMAND
+func (attr ProcAttr) args() []string {
+	var ret []string
+
+	if attr.Nice != "" {
+		ret = append(ret, "nice="+attr.Nice)
+	}
+
+	if attr.IONice != "" {
+		ret = append(ret, "ionice="+attr.IONice)
+	}
+
+	if attr.RlimitNofile != "" {
+		ret = append(ret, "rlimit-nofile="+attr.RlimitNofile)
+	}
+
+	if attr.RlimitAs != "" {
+		ret = append(ret, "rlimit-as="+attr.RlimitAs)
+	}
+
+	return ret
+}
+
+// Let command run through PROCATTR_EXEC_COMMAND which applies the process attributes
+// and replaces itself with the cronjob command
+func procAttrWrap(execCmd *exec.Cmd, attr ProcAttr) error {
+	if attr.IsEmpty() {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{self, PROCATTR_EXEC_COMMAND}
+	args = append(args, attr.args()...)
+	args = append(args, "--", execCmd.Path)
+	args = append(args, execCmd.Args...)
+
+	execCmd.Path = self
+	execCmd.Args = args
+
+	return nil
+}
+
+// Apply process attributes and exec command (format: attr=value... -- path argv...)
+func procAttrExec(args []string) {
+	// nice and ionice are applied per thread
+	runtime.LockOSThread()
+
+	for len(args) >= 1 && args[0] != "--" {
+		if err := procAttrApply(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%v\n", LogPrefix, err)
+			os.Exit(126)
+		}
+		args = args[1:]
+	}
+
+	if len(args) < 3 {
+		fmt.Fprintf(os.Stderr, "%smissing command for %s\n", LogPrefix, PROCATTR_EXEC_COMMAND)
+		os.Exit(126)
+	}
+
+	err := syscall.Exec(args[1], args[2:], os.Environ())
+	fmt.Fprintf(os.Stderr, "%sexec %s failed: %v\n", LogPrefix, args[1], err)
+	os.Exit(126)
+}
+
+func procAttrApply(arg string) error {
+	split := strings.SplitN(arg, "=", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("invalid process attribute %q", arg)
+	}
+	name, value := split[0], split[1]
+
+	switch name {
+	case "nice":
+		nice, err := parseNice(value)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice); err != nil {
+			return fmt.Errorf("cannot set nice %d: %v", nice, err)
+		}
+	case "ionice":
+		class, level, err := parseIONice(value)
+		if err != nil {
+			return err
+		}
+		if err := ioprioSet(class, level); err != nil {
+			return fmt.Errorf("cannot set ionice %s: %v", value, err)
+		}
+	case "rlimit-nofile":
+		rlimit, err := parseRlimit(value)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+			return fmt.Errorf("cannot set RLIMIT_NOFILE %s: %v", value, err)
+		}
+	case "rlimit-as":
+		rlimit, err := parseRlimit(value)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setrlimit(syscall.RLIMIT_AS, &rlimit); err != nil {
+			return fmt.Errorf("cannot set RLIMIT_AS %s: %v", value, err)
+		}
+	default:
+		return fmt.Errorf("unknown process attribute %q", name)
+	}
+
+	return nil
+}
+
+// Parse nice value (-20 to 19)
+func parseNice(value string) (int, error) {
+	nice, err := strconv.Atoi(value)
+	if err != nil || nice < -20 || nice > 19 {
+		return 0, fmt.Errorf("invalid nice value %q (expected -20 to 19)", value)
+	}
+
+	return nice, nil
+}
+
+// Parse ionice value (format: idle, best-effort[:level], realtime[:level]; level 0-7)
+func parseIONice(value string) (int, int, error) {
+	split := strings.SplitN(value, ":", 2)
+	level := 4
+
+	if len(split) == 2 {
+		var err error
+		level, err = strconv.Atoi(split[1])
+		if err != nil || level < 0 || level > 7 {
+			return 0, 0, fmt.Errorf("invalid ionice level %q (expected 0 to 7)", split[1])
+		}
+	}
+
+	switch split[0] {
+	case "idle":
+		return IOPRIO_CLASS_IDLE, 0, nil
+	case "best-effort":
+		return IOPRIO_CLASS_BEST_EFFORT, level, nil
+	case "realtime":
+		return IOPRIO_CLASS_REALTIME, level, nil
+	}
+
+	return 0, 0, fmt.Errorf("invalid ionice class %q (expected idle, best-effort or realtime)", split[0])
+}
+
+// Parse resource limit (format: soft[:hard]; values: unlimited or number with optional K, M, G suffix)
+func parseRlimit(value string) (syscall.Rlimit, error) {
+	split := strings.SplitN(value, ":", 2)
+
+	soft, err := parseRlimitValue(split[0])
+	if err != nil {
+		return syscall.Rlimit{}, err
+	}
+
+	hard := soft
+	if len(split) == 2 {
+		hard, err = parseRlimitValue(split[1])
+		if err != nil {
+			return syscall.Rlimit{}, err
+		}
+	}
+
+	if soft > hard {
+		return syscall.Rlimit{}, fmt.Errorf("invalid resource limit %q (soft limit above hard limit)", value)
+	}
+
+	return syscall.Rlimit{Cur: soft, Max: hard}, nil
+}
+
+func parseRlimitValue(value string) (uint64, error) {
+	if value == "unlimited" || value == "infinity" {
+		return ^uint64(0), nil
+	}
+
+	ret, err := parseByteSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource limit value %q", value)
+	}
+
+	return ret, nil
+}
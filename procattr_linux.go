@@ -0,0 +1,22 @@
+package main
+
+import (
+	"syscall"
+)
+
+const (
+	IOPRIO_WHO_PROCESS = 1
+	IOPRIO_CLASS_SHIFT = 13
+)
+
+// Set io scheduling class and level of current thread
+func ioprioSet(class int, level int) error {
+	ioprio := class<<IOPRIO_CLASS_SHIFT | level
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, IOPRIO_WHO_PROCESS, 0, uintptr(ioprio))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+func ioprioSet(class int, level int) error {
+	return errors.New("ionice is not supported on this platform")
+}
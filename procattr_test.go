@@ -0,0 +1,127 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseNice(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected int
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"10", 10, true},
+		{"-20", -20, true},
+		{"19", 19, true},
+		{"20", 0, false},
+		{"-21", 0, false},
+		{"low", 0, false},
+		{"", 0, false},
+	} {
+		nice, err := parseNice(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("parseNice(%q): expected valid %v, got error %v", test.value, test.valid, err)
+			continue
+		}
+		if nice != test.expected {
+			t.Errorf("parseNice(%q): expected %d, got %d", test.value, test.expected, nice)
+		}
+	}
+}
+
+func TestParseIONice(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		class int
+		level int
+		valid bool
+	}{
+		{"idle", IOPRIO_CLASS_IDLE, 0, true},
+		{"idle:3", IOPRIO_CLASS_IDLE, 0, true},
+		{"best-effort", IOPRIO_CLASS_BEST_EFFORT, 4, true},
+		{"best-effort:7", IOPRIO_CLASS_BEST_EFFORT, 7, true},
+		{"realtime:0", IOPRIO_CLASS_REALTIME, 0, true},
+		{"best-effort:8", 0, 0, false},
+		{"best-effort:-1", 0, 0, false},
+		{"realtime:high", 0, 0, false},
+		{"besteffort", 0, 0, false},
+		{"", 0, 0, false},
+	} {
+		class, level, err := parseIONice(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("parseIONice(%q): expected valid %v, got error %v", test.value, test.valid, err)
+			continue
+		}
+		if class != test.class || level != test.level {
+			t.Errorf("parseIONice(%q): expected %d:%d, got %d:%d", test.value, test.class, test.level, class, level)
+		}
+	}
+}
+
+func TestParseRlimit(t *testing.T) {
+	unlimited := ^uint64(0)
+
+	for _, test := range []struct {
+		value    string
+		expected syscall.Rlimit
+		valid    bool
+	}{
+		{"1024", syscall.Rlimit{Cur: 1024, Max: 1024}, true},
+		{"1024:4096", syscall.Rlimit{Cur: 1024, Max: 4096}, true},
+		{"512M", syscall.Rlimit{Cur: 512 << 20, Max: 512 << 20}, true},
+		{"1G:2G", syscall.Rlimit{Cur: 1 << 30, Max: 2 << 30}, true},
+		{"4K:unlimited", syscall.Rlimit{Cur: 4 << 10, Max: unlimited}, true},
+		{"infinity", syscall.Rlimit{Cur: unlimited, Max: unlimited}, true},
+		{"4096:1024", syscall.Rlimit{}, false},
+		{"unlimited:1024", syscall.Rlimit{}, false},
+		{"1T", syscall.Rlimit{}, false},
+		{"-1", syscall.Rlimit{}, false},
+		{"1024:", syscall.Rlimit{}, false},
+		{"", syscall.Rlimit{}, false},
+	} {
+		rlimit, err := parseRlimit(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("parseRlimit(%q): expected valid %v, got error %v", test.value, test.valid, err)
+			continue
+		}
+		if uint64(rlimit.Cur) != uint64(test.expected.Cur) || uint64(rlimit.Max) != uint64(test.expected.Max) {
+			t.Errorf("parseRlimit(%q): expected %+v, got %+v", test.value, test.expected, rlimit)
+		}
+	}
+}
+
+func TestProcAttrValidate(t *testing.T) {
+	for _, test := range []struct {
+		attr  ProcAttr
+		valid bool
+	}{
+		{ProcAttr{}, true},
+		{ProcAttr{Nice: "10", IONice: "idle", RlimitNofile: "1024:4096", RlimitAs: "2G"}, true},
+		{ProcAttr{Nice: "99"}, false},
+		{ProcAttr{IONice: "fast"}, false},
+		{ProcAttr{RlimitNofile: "many"}, false},
+		{ProcAttr{RlimitAs: "2G:1G"}, false},
+	} {
+		if err := test.attr.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%v): expected valid %v, got error %v", test.attr, test.valid, err)
+		}
+	}
+}
+
+func TestProcAttrArgs(t *testing.T) {
+	attr := ProcAttr{Nice: "10", IONice: "best-effort:2", RlimitNofile: "1024", RlimitAs: "1G"}
+	expected := []string{"nice=10", "ionice=best-effort:2", "rlimit-nofile=1024", "rlimit-as=1G"}
+
+	args := attr.args()
+	if len(args) != len(expected) {
+		t.Fatalf("expected args %v, got %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("expected args %v, got %v", expected, args)
+			break
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	REDACT_MASK = "***"
+
+	// shorter environment values are not redacted (would mask too much)
+	REDACT_MIN_LENGTH = 4
+)
+
+var (
+	redactor = &Redactor{}
+)
+
+// Masks secrets (regex matches and values of environment variables) in logs and web interface
+type Redactor struct {
+	mu            sync.RWMutex
+	patterns      []*regexp.Regexp
+	envValues     []string
+	crontabValues []string
+}
+
+// Compile --redact patterns and collect values of --redact-env variables from daemon environment
+func (r *Redactor) Init(patterns []string, envNames []string) error {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.patterns = compiled
+	r.envValues = redactEnvValues(os.Environ(), envNames)
+
+	return nil
+}
+
+// Collect values of --redact-env variables defined in crontabs
+func (r *Redactor) SetCrontabs(entries []CrontabEntry) {
+	var environ []string
+	for _, entry := range entries {
+		environ = append(environ, entry.Env...)
+	}
+
+	values := redactEnvValues(environ, opts.RedactEnv)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.crontabValues = values
+}
+
+// Mask all secrets in string
+func (r *Redactor) Redact(str string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, value := range r.envValues {
+		str = strings.Replace(str, value, REDACT_MASK, -1)
+	}
+
+	for _, value := range r.crontabValues {
+		str = strings.Replace(str, value, REDACT_MASK, -1)
+	}
+
+	for _, re := range r.patterns {
+		str = redactRegexp(re, str)
+	}
+
+	return str
+}
+
+// Mask regex matches, if pattern has groups only the groups are masked
+func redactRegexp(re *regexp.Regexp, str string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(str, REDACT_MASK)
+	}
+
+	var buf strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(str, -1) {
+		for group := 1; group <= re.NumSubexp(); group++ {
+			start, end := match[group*2], match[group*2+1]
+			if start < last || start < 0 {
+				continue
+			}
+			buf.WriteString(str[last:start])
+			buf.WriteString(REDACT_MASK)
+			last = end
+		}
+	}
+	buf.WriteString(str[last:])
+
+	return buf.String()
+}
+
+// Return values (longest first) of environment variables matching name patterns
+func redactEnvValues(environ []string, names []string) []string {
+	var ret []string
+	for _, env := range environ {
+		split := strings.SplitN(env, "=", 2)
+		if len(split) == 2 && len(split[1]) >= REDACT_MIN_LENGTH && envNameMatches(split[0], names) {
+			ret = append(ret, split[1])
+		}
+	}
+
+	// replace longest values first, values could contain each other
+	sort.Slice(ret, func(i, j int) bool { return len(ret[i]) > len(ret[j]) })
+
+	return ret
+}
+
+// Mask secrets in message and string fields of log record
+func redactLogRecord(record LogRecord) LogRecord {
+	record.Message = redactor.Redact(record.Message)
+	record.Text = redactor.Redact(record.Text)
+
+	fields := make([]LogField, len(record.Fields))
+	for i, field := range record.Fields {
+		if value, ok := field.Value.(string); ok {
+			field.Value = redactor.Redact(value)
+		}
+		fields[i] = field
+	}
+	record.Fields = fields
+
+	return record
+}
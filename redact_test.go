@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestRedactRegexp(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		str      string
+		expected string
+	}{
+		{`sk_live_[a-z0-9]+`, "key sk_live_abc123 used", "key *** used"},
+		{`token=(\w+)`, "url?token=abc&token=def", "url?token=***&token=***"},
+		{`user=(\w+) password=(\w+)`, "user=admin password=secret", "user=*** password=***"},
+		{`password=(\w+)?`, "password= password=x", "password= password=***"},
+		{`secret`, "nothing to hide", "nothing to hide"},
+		{`(a)|b`, "ab", "***b"},
+	} {
+		re := regexp.MustCompile(test.pattern)
+		if redacted := redactRegexp(re, test.str); redacted != test.expected {
+			t.Errorf("redactRegexp(%q, %q): expected %q, got %q", test.pattern, test.str, test.expected, redacted)
+		}
+	}
+}
+
+func TestRedactEnvValues(t *testing.T) {
+	environ := []string{"DB_PASSWORD=secret", "API_TOKEN=secret-token", "SHORT_TOKEN=abc", "TZ=Europe/Berlin", "EMPTY"}
+
+	values := redactEnvValues(environ, []string{"*_PASSWORD", "*_TOKEN", "EMPTY"})
+	expected := []string{"secret-token", "secret"}
+
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+	opts.RedactEnv = []string{"*_TOKEN"}
+
+	t.Setenv("GO_CROND_TEST_TOKEN", "daemon-token")
+
+	r := &Redactor{}
+	if err := r.Init([]string{`Bearer (\S+)`}, opts.RedactEnv); err != nil {
+		t.Fatal(err)
+	}
+	r.SetCrontabs([]CrontabEntry{{Env: []string{"API_TOKEN=crontab-token", "TZ=UTC"}}})
+
+	for _, test := range []struct {
+		str      string
+		expected string
+	}{
+		{"using daemon-token", "using ***"},
+		{"using crontab-token", "using ***"},
+		{"Authorization: Bearer abc.def", "Authorization: Bearer ***"},
+		{"TZ=UTC", "TZ=UTC"},
+	} {
+		if redacted := r.Redact(test.str); redacted != test.expected {
+			t.Errorf("Redact(%q): expected %q, got %q", test.str, test.expected, redacted)
+		}
+	}
+
+	// crontab values are replaced on reload
+	r.SetCrontabs(nil)
+	if redacted := r.Redact("using crontab-token"); redacted != "using crontab-token" {
+		t.Errorf("expected crontab value of previous reload to be unmasked, got %q", redacted)
+	}
+
+	if err := r.Init([]string{`(`}, nil); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// reload requests of api and --watch
+	reloadRequests = make(chan reloadRequest)
+)
+
+// Reload request, the reply channel receives the result of the reload
+type reloadRequest struct {
+	reason string
+	reply  chan ReloadResult
+}
+
+// Result of (re)loading crontabs (POST /api/v1/reload), secrets are redacted
+type ReloadResult struct {
+	Time      time.Time   `json:"time"`
+	Success   bool        `json:"success"`
+	Applied   bool        `json:"applied"` // failed reloads are not applied (except on startup)
+	Jobs      int         `json:"jobs"`    // number of scheduled jobs
+	Added     []ReloadJob `json:"added"`
+	Removed   []ReloadJob `json:"removed"`
+	Unchanged int         `json:"unchanged"`
+	Errors    []string    `json:"errors"`
+}
+
+// Cronjob in diff of reload
+type ReloadJob struct {
+	Name    string `json:"name,omitempty"`
+	Spec    string `json:"spec"`
+	User    string `json:"user"`
+	Command string `json:"command"`
+	Source  string `json:"source"`
+}
+
+// Errors of crontabs and cronjobs which failed to load, secrets are redacted
+type LoadError struct {
+	Errors []string
+}
+
+// Add error, errors of a LoadError are added individually
+func (loadErr *LoadError) Add(err error) {
+	if nested, ok := err.(*LoadError); ok {
+		if nested != nil {
+			loadErr.Errors = append(loadErr.Errors, nested.Errors...)
+		}
+	} else if err != nil {
+		loadErr.Errors = append(loadErr.Errors, redactor.Redact(err.Error()))
+	}
+}
+
+// Return LoadError if errors were added, nil otherwise
+func (loadErr *LoadError) ErrorOrNil() error {
+	if len(loadErr.Errors) == 0 {
+		return nil
+	}
+	return loadErr
+}
+
+func (loadErr *LoadError) Error() string {
+	if len(loadErr.Errors) == 1 {
+		return loadErr.Errors[0]
+	}
+	return fmt.Sprintf("%d errors: %s", len(loadErr.Errors), strings.Join(loadErr.Errors, "; "))
+}
+
+// Request reload of crontabs and wait for the result
+func reloadAndWait(ctx context.Context, reason string) (ReloadResult, error) {
+	reply := make(chan ReloadResult, 1)
+
+	select {
+	case reloadRequests <- reloadRequest{reason: reason, reply: reply}:
+	case <-ctx.Done():
+		return ReloadResult{}, ctx.Err()
+	}
+
+	select {
+	case result := <-reply:
+		return result, nil
+	case <-ctx.Done():
+		return ReloadResult{}, ctx.Err()
+	}
+}
+
+// Create result of reload, jobs are compared by source, spec, user and command
+//
+// The diff contains the changes of the loaded jobs, also if they were not applied.
+func newReloadResult(previous []Job, loaded []Job, applied bool, err error) ReloadResult {
+	ret := ReloadResult{
+		Time:    time.Now(),
+		Success: err == nil,
+		Applied: applied,
+		Jobs:    len(previous),
+		Added:   []ReloadJob{},
+		Removed: []ReloadJob{},
+		Errors:  []string{},
+	}
+
+	remaining := map[string]int{}
+	for _, job := range previous {
+		remaining[job.key]++
+	}
+
+	if applied {
+		ret.Jobs = len(loaded)
+	}
+
+	for _, job := range loaded {
+		if remaining[job.key] > 0 {
+			remaining[job.key]--
+			ret.Unchanged++
+		} else {
+			ret.Added = append(ret.Added, newReloadJob(job))
+		}
+	}
+
+	for _, job := range previous {
+		if remaining[job.key] > 0 {
+			remaining[job.key]--
+			ret.Removed = append(ret.Removed, newReloadJob(job))
+		}
+	}
+
+	if err != nil {
+		loadErr := &LoadError{}
+		loadErr.Add(err)
+		ret.Errors = loadErr.Errors
+	}
+
+	return ret
+}
+
+func newReloadJob(job Job) ReloadJob {
+	return ReloadJob{
+		Name:    job.Name,
+		Spec:    job.Spec,
+		User:    job.User,
+		Command: job.Command,
+		Source:  job.Source,
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func testReloadJobs(specs ...string) []Job {
+	jobs := []Job{}
+	for _, spec := range specs {
+		cronjob := CrontabEntry{Spec: spec, User: "root", Command: "true", Source: "/etc/crontab"}
+		jobs = append(jobs, newJob(0, 0, cronjob))
+	}
+	return jobs
+}
+
+func testReloadSpecs(jobs []ReloadJob) []string {
+	specs := []string{}
+	for _, job := range jobs {
+		specs = append(specs, job.Spec)
+	}
+	return specs
+}
+
+func TestNewReloadResult(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		previous  []Job
+		loaded    []Job
+		applied   bool
+		err       error
+		jobs      int
+		added     []string
+		removed   []string
+		unchanged int
+		errors    []string
+	}{
+		{
+			"startup",
+			testReloadJobs(),
+			testReloadJobs("@daily", "@hourly"),
+			true, nil,
+			2, []string{"@daily", "@hourly"}, []string{}, 0, []string{},
+		},
+		{
+			"unchanged",
+			testReloadJobs("@daily", "@hourly"),
+			testReloadJobs("@hourly", "@daily"),
+			true, nil,
+			2, []string{}, []string{}, 2, []string{},
+		},
+		{
+			"changed",
+			testReloadJobs("@daily", "@hourly"),
+			testReloadJobs("@daily", "@weekly"),
+			true, nil,
+			2, []string{"@weekly"}, []string{"@hourly"}, 1, []string{},
+		},
+		{
+			"duplicates",
+			testReloadJobs("@daily", "@daily"),
+			testReloadJobs("@daily", "@daily", "@daily"),
+			true, nil,
+			3, []string{"@daily"}, []string{}, 2, []string{},
+		},
+		{
+			"removed duplicate",
+			testReloadJobs("@daily", "@daily"),
+			testReloadJobs("@daily"),
+			true, nil,
+			1, []string{}, []string{"@daily"}, 1, []string{},
+		},
+		{
+			"failed",
+			testReloadJobs("@daily", "@hourly"),
+			testReloadJobs("@daily"),
+			false, &LoadError{Errors: []string{"crontab a", "crontab b"}},
+			2, []string{}, []string{"@hourly"}, 1, []string{"crontab a", "crontab b"},
+		},
+		{
+			"failed on startup",
+			testReloadJobs(),
+			testReloadJobs("@daily"),
+			true, errors.New("missing crontab"),
+			1, []string{"@daily"}, []string{}, 0, []string{"missing crontab"},
+		},
+	} {
+		result := newReloadResult(test.previous, test.loaded, test.applied, test.err)
+
+		if result.Success != (test.err == nil) || result.Applied != test.applied {
+			t.Errorf("%s: expected success %v and applied %v, got %v and %v", test.name, test.err == nil, test.applied, result.Success, result.Applied)
+		}
+		if result.Jobs != test.jobs {
+			t.Errorf("%s: expected %d jobs, got %d", test.name, test.jobs, result.Jobs)
+		}
+		if added := testReloadSpecs(result.Added); fmt.Sprint(added) != fmt.Sprint(test.added) {
+			t.Errorf("%s: expected added %v, got %v", test.name, test.added, added)
+		}
+		if removed := testReloadSpecs(result.Removed); fmt.Sprint(removed) != fmt.Sprint(test.removed) {
+			t.Errorf("%s: expected removed %v, got %v", test.name, test.removed, removed)
+		}
+		if result.Unchanged != test.unchanged {
+			t.Errorf("%s: expected %d unchanged, got %d", test.name, test.unchanged, result.Unchanged)
+		}
+		if fmt.Sprint(result.Errors) != fmt.Sprint(test.errors) {
+			t.Errorf("%s: expected errors %v, got %v", test.name, test.errors, result.Errors)
+		}
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	loadErr := &LoadError{}
+	if loadErr.ErrorOrNil() != nil {
+		t.Error("expected nil without errors")
+	}
+
+	loadErr.Add(nil)
+	loadErr.Add((*LoadError)(nil))
+	loadErr.Add(errors.New("first"))
+	loadErr.Add(&LoadError{Errors: []string{"second", "third"}})
+
+	if fmt.Sprint(loadErr.Errors) != "[first second third]" {
+		t.Errorf("unexpected errors %v", loadErr.Errors)
+	}
+	if err := loadErr.ErrorOrNil(); err == nil || err.Error() != "3 errors: first; second; third" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
-	"os"
+	"fmt"
+	"hash/fnv"
 	"os/exec"
-	"os/user"
-	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -13,112 +14,247 @@ import (
 )
 
 type Job struct {
-	Id      int
-	cronId  cron.EntryID
-	Name    string
-	Updated bool
-	Status  error
-	Elapsed time.Duration
+	Id        int
+	cronId    cron.EntryID
+	Name      string
+	Spec      string
+	User      string
+	Command   string
+	Source    string
+	Tags      []string
+	Next      time.Time
+	Prev      time.Time
+	Running   int
+	Paused    bool
+	Updated   bool
+	Status    error
+	OOMKilled bool
+	TimedOut  bool
+	Elapsed   time.Duration
+	ProcAttr  ProcAttr
+	Env       []string
+	key       string
+	run       func(runId uint64, manual bool)
+}
+
+// Jobs of loaded crontabs with their scheduler
+type JobSet struct {
+	cron *cron.Cron
+	jobs []Job
+	ids  map[int]bool
 }
 
 type Runner struct {
-	cron   *cron.Cron
-	jobsMu sync.Mutex
-	jobs   []Job
-	nextId int
+	cron      *cron.Cron
+	jobsMu    sync.Mutex
+	jobs      []Job
+	nextRunId uint64
+	history   *RunHistory
+	streamsMu sync.Mutex
+	streams   map[uint64]*OutputBroadcaster
+	paused    map[string]bool
 }
 
 func NewRunner() *Runner {
 	r := &Runner{
-		jobsMu: sync.Mutex{},
+		jobsMu:  sync.Mutex{},
+		history: NewRunHistory(opts.RunHistory),
+		streams: map[uint64]*OutputBroadcaster{},
+		paused:  map[string]bool{},
 	}
 	return r
 }
 
-// Recreate crontab jobs
-func (r *Runner) CreateCronjobs(crontabEntries []CrontabEntry) error {
-	r.cron = cron.New()
-	r.jobs = []Job{}
+// Create jobs of crontab entries, returns LoadError if cronjobs failed to load
+//
+// The jobs are not scheduled until the job set is passed to Swap.
+func (r *Runner) CreateCronjobs(crontabEntries []CrontabEntry) (*JobSet, error) {
+	set := &JobSet{cron: cron.New(), jobs: []Job{}, ids: map[int]bool{}}
 
+	loadErr := &LoadError{}
 	for _, crontabEntry := range crontabEntries {
-		if opts.EnableUserSwitching {
-			r.AddWithUser(crontabEntry)
+		err := validateCrontabEntry(&crontabEntry)
+		if err != nil {
+			LoggerError.Printf("Failed add cron job %v; Error:%v", LoggerError.CronjobToString(crontabEntry), err)
+		} else if opts.EnableUserSwitching {
+			err = r.AddWithUser(set, crontabEntry)
 		} else {
-			r.Add(crontabEntry)
+			err = r.Add(set, crontabEntry)
+		}
+
+		if err != nil {
+			loadErr.Add(fmt.Errorf("%v: %v", LoggerError.CronjobToString(crontabEntry), err))
 		}
 	}
 
-	return nil
+	return set, loadErr.ErrorOrNil()
 }
 
-// Add crontab entry
-func (r *Runner) Add(cronjob CrontabEntry) error {
-	cronSpec := cronjob.Spec
+// Replace scheduled jobs with job set, running cronjobs of the previous set are not killed
+//
+// State of unchanged jobs (running, result of last run) is kept.
+func (r *Runner) Swap(set *JobSet) {
+	if r.cron != nil {
+		r.Stop()
+	}
+
 	r.jobsMu.Lock()
-	defer r.jobsMu.Unlock()
+	previous := map[int]Job{}
+	for _, job := range r.jobs {
+		previous[job.Id] = job
+	}
+
+	for i, job := range set.jobs {
+		if state, ok := previous[job.Id]; ok {
+			job.Running = state.Running
+			job.Updated = state.Updated
+			job.Status = state.Status
+			job.OOMKilled = state.OOMKilled
+			job.TimedOut = state.TimedOut
+			job.Elapsed = state.Elapsed
+			set.jobs[i] = job
+		}
+	}
+
+	r.cron = set.cron
+	r.jobs = set.jobs
+	r.jobsMu.Unlock()
+
+	r.Start()
+}
+
+// Validate settings of crontab entry and parse its notification template
+func validateCrontabEntry(crontabEntry *CrontabEntry) error {
+	if err := crontabEntry.Cgroup.Validate(); err != nil {
+		return err
+	}
+
+	if err := crontabEntry.ProcAttr.Validate(); err != nil {
+		return err
+	}
+
+	if err := crontabEntry.EnvPolicy.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := cronjobTimeout(*crontabEntry); err != nil {
+		return err
+	}
+
+	if err := crontabEntry.Notify.Load(); err != nil {
+		return err
+	}
+
+	return pingUrlValidate(crontabEntry.PingUrl)
+}
+
+// Create job of crontab entry (secrets are redacted)
+func newJob(id int, cronId cron.EntryID, cronjob CrontabEntry) Job {
+	return Job{
+		Id:       id,
+		cronId:   cronId,
+		Name:     redactor.Redact(cronjob.Name),
+		Spec:     cronjob.Spec,
+		User:     cronjob.User,
+		Command:  redactor.Redact(cronjob.Command),
+		Source:   cronjob.Source,
+		Tags:     cronjob.Tags,
+		ProcAttr: cronjob.ProcAttr,
+		key:      jobKey(cronjob),
+	}
+}
+
+// Return key of crontab entry, used for keeping state (eg. paused) across reloads
+func jobKey(cronjob CrontabEntry) string {
+	return strings.Join([]string{cronjob.Source, cronjob.Spec, cronjob.User, cronjob.Command}, "\x00")
+}
+
+// Return id of job, derived from the job key to be stable across reloads and restarts
+//
+// Duplicate keys (and hash collisions) get the next free id.
+func (set *JobSet) newJobId(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	id := int(hash.Sum32() & 0x7fffffff)
+	for set.ids[id] {
+		id = (id + 1) & 0x7fffffff
+	}
+	set.ids[id] = true
+
+	return id
+}
+
+// Add crontab entry to job set
+func (r *Runner) Add(set *JobSet, cronjob CrontabEntry) error {
+	cronSpec := cronjob.Spec
+	jobId := set.newJobId(jobKey(cronjob))
 
-	id, err := r.cron.AddFunc(cronSpec, r.cmdFunc(r.nextId, cronjob, func(execCmd *exec.Cmd) bool {
+	run := r.cmdFunc(jobId, cronjob, func(execCmd *exec.Cmd) bool {
 		// before exec callback
-		LoggerInfo.CronjobExec(cronjob)
 		return true
-	}))
+	})
+	id, err := set.cron.AddFunc(cronSpec, func() { run(r.newRunId(), false) })
 
 	if err != nil {
 		LoggerError.Printf("Failed add cron job spec:%v cmd:%v err:%v", cronjob.Spec, cronjob.Command, err)
 	} else {
-		LoggerInfo.CronjobAdd(cronjob)
+		LoggerInfo.CronjobAdd(jobId, cronjob)
 
-		r.jobs = append(r.jobs, Job{Id: r.nextId, cronId: id, Name: cronjob.Name})
-		r.nextId++
+		job := newJob(jobId, id, cronjob)
+		job.run = run
+		job.Env = maskEnvironment(cronjobEnvironment(cronjob, nil))
+		set.jobs = append(set.jobs, job)
 	}
 
 	return err
 }
 
-// Add crontab entry with user
-func (r *Runner) AddWithUser(cronjob CrontabEntry) error {
+// Add crontab entry with user to job set
+func (r *Runner) AddWithUser(set *JobSet, cronjob CrontabEntry) error {
 	cronSpec := cronjob.Spec
-	r.jobsMu.Lock()
-	defer r.jobsMu.Unlock()
 
-	id, err := r.cron.AddFunc(cronSpec, r.cmdFunc(r.nextId, cronjob, func(execCmd *exec.Cmd) bool {
-		// before exec callback
-		LoggerInfo.CronjobExec(cronjob)
+	// resolve user and group at load time
+	identity, err := identityCache.Get(cronjob.User)
+	if err != nil {
+		LoggerError.Printf("Failed add cron job %v; Error:%v", LoggerError.CronjobToString(cronjob), err)
+		return err
+	}
 
-		// lookup username
-		u, err := user.Lookup(cronjob.User)
+	jobId := set.newJobId(jobKey(cronjob))
+	run := r.cmdFunc(jobId, cronjob, func(execCmd *exec.Cmd) bool {
+		// before exec callback
+		// lookup user and group (cached)
+		identity, err := identityCache.Get(cronjob.User)
 		if err != nil {
 			LoggerError.Printf("user lookup failed: %v", err)
 			return false
 		}
 
-		// convert userid to int
-		userId, err := strconv.ParseUint(u.Uid, 10, 32)
-		if err != nil {
-			LoggerError.Printf("Cannot convert user to id:%v", err)
-			return false
-		}
-
-		// convert groupid to int
-		groupId, err := strconv.ParseUint(u.Gid, 10, 32)
-		if err != nil {
-			LoggerError.Printf("Cannot convert group to id:%v", err)
-			return false
-		}
-
 		// add process credentials
 		execCmd.SysProcAttr = &syscall.SysProcAttr{}
-		execCmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(userId), Gid: uint32(groupId)}
+		execCmd.SysProcAttr.Credential = &syscall.Credential{Uid: identity.Uid, Gid: identity.Gid, Groups: identity.Groups}
+
+		// login environment of user, crontab variables take precedence
+		execCmd.Env = cronjobEnvironment(cronjob, identity.Environment())
+
+		// run in home directory of user if PWD is not set
+		if cronjob.Pwd == "" && checkIfDirectoryExists(identity.HomeDir) {
+			execCmd.Dir = identity.HomeDir
+		}
 		return true
-	}))
+	})
+	id, err := set.cron.AddFunc(cronSpec, func() { run(r.newRunId(), false) })
 
 	if err != nil {
 		LoggerError.Printf("Failed add cron job %v; Error:%v", LoggerError.CronjobToString(cronjob), err)
 	} else {
-		LoggerInfo.Printf("Add cron job %v", LoggerError.CronjobToString(cronjob))
+		LoggerInfo.CronjobAdd(jobId, cronjob)
 
-		r.jobs = append(r.jobs, Job{Id: r.nextId, cronId: id, Name: cronjob.Name})
-		r.nextId++
+		job := newJob(jobId, id, cronjob)
+		job.run = run
+		job.Env = maskEnvironment(cronjobEnvironment(cronjob, identity.Environment()))
+		set.jobs = append(set.jobs, job)
 	}
 
 	return err
@@ -126,17 +262,30 @@ func (r *Runner) AddWithUser(cronjob CrontabEntry) error {
 
 // Return number of jobs
 func (r *Runner) Len() int {
-	return len(r.cron.Entries())
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	return len(r.jobs)
 }
 
 // Start runner
 func (r *Runner) Start() {
 	LoggerInfo.Printf("Start runner with %d jobs\n", r.Len())
+
+	// heartbeat is run by the scheduler, stops ticking if the scheduler hangs
+	r.cron.Schedule(cron.Every(HEARTBEAT_INTERVAL), cron.FuncJob(heartbeat.Tick))
+
+	if opts.JobLogDir != "" {
+		r.cron.Schedule(cron.Every(JOB_LOG_CLEANUP_INTERVAL), cron.FuncJob(func() { jobLogCleanupAll(r.GetJobs()) }))
+		go jobLogCleanupAll(r.GetJobs())
+	}
 	r.cron.Start()
+	heartbeat.Started()
 }
 
 // Stop runner
 func (r *Runner) Stop() {
+	heartbeat.Stopped()
 	r.cron.Stop()
 	LoggerInfo.Println("Stop runner")
 }
@@ -148,14 +297,119 @@ func (r *Runner) GetJobs() []Job {
 	var entries = make([]Job, len(r.jobs))
 
 	for i, e := range r.jobs {
+		entry := r.cron.Entry(e.cronId)
+		e.Next, e.Prev = entry.Next, entry.Prev
+		e.Paused = r.paused[e.key]
 		entries[i] = e
 	}
 	return entries
 }
 
+// Return job by id
+func (r *Runner) GetJob(id int) (Job, bool) {
+	for _, job := range r.GetJobs() {
+		if job.Id == id {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+// Run job now (also if paused), returns id of run
+func (r *Runner) RunNow(id int) (uint64, error) {
+	job, ok := r.GetJob(id)
+	if !ok {
+		return 0, fmt.Errorf("job %d not found", id)
+	}
+
+	runId := r.newRunId()
+	go job.run(runId, true)
+
+	return runId, nil
+}
+
+// Pause or resume scheduled runs of job (kept across reloads)
+func (r *Runner) SetPaused(id int, paused bool) error {
+	job, ok := r.GetJob(id)
+	if !ok {
+		return fmt.Errorf("job %d not found", id)
+	}
+
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	if paused {
+		r.paused[job.key] = true
+	} else {
+		delete(r.paused, job.key)
+	}
+
+	return nil
+}
+
+func (r *Runner) isPaused(key string) bool {
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	return r.paused[key]
+}
+
+func (r *Runner) newRunId() uint64 {
+	return atomic.AddUint64(&r.nextRunId, 1)
+}
+
+// Return run history
+func (r *Runner) History() *RunHistory {
+	return r.history
+}
+
+// Return output stream of running run
+func (r *Runner) Stream(runId uint64) (*OutputBroadcaster, bool) {
+	r.streamsMu.Lock()
+	defer r.streamsMu.Unlock()
+
+	stream, ok := r.streams[runId]
+	return stream, ok
+}
+
+func (r *Runner) addStream(runId uint64, stream *OutputBroadcaster) {
+	r.streamsMu.Lock()
+	defer r.streamsMu.Unlock()
+
+	r.streams[runId] = stream
+}
+
+func (r *Runner) removeStream(runId uint64) {
+	r.streamsMu.Lock()
+	defer r.streamsMu.Unlock()
+
+	delete(r.streams, runId)
+}
+
+// Update job by id (if it still exists after reloads)
+func (r *Runner) updateJob(id int, update func(job *Job)) {
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	for i := range r.jobs {
+		if r.jobs[i].Id == id {
+			update(&r.jobs[i])
+		}
+	}
+}
+
 // Execute crontab command
-func (r *Runner) cmdFunc(id int, cronjob CrontabEntry, cmdCallback func(*exec.Cmd) bool) func() {
-	cmdFunc := func() {
+func (r *Runner) cmdFunc(id int, cronjob CrontabEntry, cmdCallback func(*exec.Cmd) bool) func(uint64, bool) {
+	key := jobKey(cronjob)
+
+	cmdFunc := func(runId uint64, manual bool) {
+		// scheduled runs of paused jobs are skipped
+		if !manual && r.isPaused(key) {
+			LoggerInfo.Verbose(fmt.Sprintf("Skipping paused cron job %v", LoggerInfo.CronjobToString(cronjob)))
+			publishJobEvent(EVENT_SKIPPED, id, runId, cronjob, nil, 0)
+			return
+		}
+
 		// fall back to normal shell if not specified
 		taskShell := cronjob.Shell
 		if taskShell == "" {
@@ -167,36 +421,140 @@ func (r *Runner) cmdFunc(id int, cronjob CrontabEntry, cmdCallback func(*exec.Cm
 		// Init command
 		execCmd := exec.Command(taskShell, "-c", cronjob.Command)
 		execCmd.Dir = cronjob.Pwd
-
-		// add custom env to cronjob
-		if len(cronjob.Env) >= 1 {
-			execCmd.Env = append(os.Environ(), cronjob.Env...)
+		if execCmd.Dir == "" {
+			execCmd.Dir = "/"
 		}
 
+		// add filtered daemon env and custom env to cronjob
+		execCmd.Env = cronjobEnvironment(cronjob, nil)
+
+		LoggerInfo.CronjobExec(id, runId, cronjob)
+		publishJobEvent(EVENT_SCHEDULED, id, runId, cronjob, nil, 0)
+
 		// exec custom callback
 		if cmdCallback(execCmd) {
+			pingStarted := pingCronjobStart(cronjob)
+
+			// stream output to subscribers (live tail)
+			stream := NewOutputBroadcaster(id, runId, start)
+			r.addStream(runId, stream)
+
+			r.history.Start(id, runId, start)
+			r.updateJob(id, func(job *Job) { job.Running++ })
+			publishJobEvent(EVENT_STARTED, id, runId, cronjob, nil, 0)
+
+			// apply nice, ionice and resource limits before job starts
+			if err := procAttrWrap(execCmd, cronjob.ProcAttr); err != nil {
+				LoggerError.Printf("Cannot apply process attributes for cron job %v: %v", LoggerError.CronjobToString(cronjob), err)
+			}
+
+			// place job into its own cgroup
+			cg, err := cgroupPrepare(execCmd, id, cronjob.Cgroup)
+			if err != nil {
+				LoggerError.Printf("Cannot create cgroup for cron job %v: %v", LoggerError.CronjobToString(cronjob), err)
+			}
 
 			// exec job
-			out, err := execCmd.CombinedOutput()
+			timeout, _ := cronjobTimeout(cronjob)
+			out, timedOut, err := runCommand(execCmd, timeout, stream)
 
 			elapsed := time.Since(start)
+			oomKilled := cg.Close()
+
+			previousFailed := false
+			r.updateJob(id, func(job *Job) {
+				previousFailed = job.Updated && job.Status != nil
+				job.Status = err
+				job.OOMKilled = oomKilled
+				job.TimedOut = timedOut
+				job.Elapsed = elapsed
+				job.Updated = true
+				job.Running--
+			})
+			r.history.Finish(runId, string(out), err, elapsed, timedOut, oomKilled)
+			stream.Finish(err, elapsed, timedOut)
+			r.removeStream(runId)
+
+			switch {
+			case timedOut:
+				publishJobEvent(EVENT_TIMED_OUT, id, runId, cronjob, err, elapsed)
+			case err != nil:
+				publishJobEvent(EVENT_FAILED, id, runId, cronjob, err, elapsed)
+			default:
+				publishJobEvent(EVENT_SUCCEEDED, id, runId, cronjob, err, elapsed)
+			}
+
+			if logErr := writeJobLog(id, runId, start, string(out), err, elapsed); logErr != nil {
+				LoggerError.Printf("Cannot write job log for cron job %v: %v", LoggerError.CronjobToString(cronjob), logErr)
+			}
 
-			for i, entry := range r.jobs {
-				if entry.Id == id {
-					r.jobsMu.Lock()
-					r.jobs[i].Status = err
-					r.jobs[i].Elapsed = elapsed
-					r.jobs[i].Updated = true
-					r.jobsMu.Unlock()
-				}
+			if oomKilled {
+				LoggerError.Printf("cronjob killed by OOM killer: cmd:%v", cronjob.Command)
+			}
+
+			if timedOut {
+				LoggerError.Printf("cronjob killed after timeout of %s: cmd:%v", timeout, cronjob.Command)
 			}
 
 			if err != nil {
-				LoggerError.CronjobExecFailed(cronjob, string(out), err, elapsed)
+				LoggerError.CronjobExecFailed(id, runId, cronjob, string(out), err, elapsed)
 			} else {
-				LoggerInfo.CronjobExecSuccess(cronjob, string(out), err, elapsed)
+				LoggerInfo.CronjobExecSuccess(id, runId, cronjob, string(out), err, elapsed)
 			}
+
+			notifyCronjobResult(id, runId, cronjob, string(out), err, elapsed, timedOut, previousFailed)
+			mailCronjobOutput(cronjob, string(out))
+			pingCronjobResult(cronjob, pingStarted, string(out), err, elapsed)
+		} else {
+			publishJobEvent(EVENT_SKIPPED, id, runId, cronjob, nil, 0)
 		}
 	}
 	return cmdFunc
 }
+
+// Return timeout of cronjob (CROND_TIMEOUT or --job-timeout, 0 for none)
+func cronjobTimeout(cronjob CrontabEntry) (time.Duration, error) {
+	if cronjob.Timeout == "" {
+		return opts.JobTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(cronjob.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %v", cronjob.Timeout, err)
+	}
+
+	return timeout, nil
+}
+
+// Run command and return combined output (streamed to subscribers), the process group is killed after the timeout
+func runCommand(execCmd *exec.Cmd, timeout time.Duration, stream *OutputBroadcaster) ([]byte, bool, error) {
+	stdout, stderr := stream.Writer("stdout"), stream.Writer("stderr")
+	execCmd.Stdout = stdout
+	execCmd.Stderr = stderr
+
+	if timeout > 0 {
+		if execCmd.SysProcAttr == nil {
+			execCmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		execCmd.SysProcAttr.Setpgid = true
+	}
+
+	if err := execCmd.Start(); err != nil {
+		return stream.Output(), false, err
+	}
+
+	var timedOut int32
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&timedOut, 1)
+			syscall.Kill(-execCmd.Process.Pid, syscall.SIGKILL)
+		})
+		defer timer.Stop()
+	}
+
+	err := execCmd.Wait()
+	stdout.flush()
+	stderr.flush()
+
+	return stream.Output(), atomic.LoadInt32(&timedOut) == 1, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJobSetNewJobId(t *testing.T) {
+	set := &JobSet{ids: map[int]bool{}}
+	first := set.newJobId("a")
+	duplicate := set.newJobId("a")
+	other := set.newJobId("b")
+
+	if first < 0 || duplicate < 0 || other < 0 {
+		t.Errorf("expected positive ids, got %d %d %d", first, duplicate, other)
+	}
+	if duplicate == first || other == first || other == duplicate {
+		t.Errorf("expected unique ids, got %d %d %d", first, duplicate, other)
+	}
+
+	// ids are derived from keys, independent of order
+	reloaded := &JobSet{ids: map[int]bool{}}
+	if id := reloaded.newJobId("b"); id != other {
+		t.Errorf("expected id %d after reload, got %d", other, id)
+	}
+	if id := reloaded.newJobId("a"); id != first {
+		t.Errorf("expected id %d after reload, got %d", first, id)
+	}
+}
+
+func TestRunnerCreateCronjobs(t *testing.T) {
+	initLogger()
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+	opts.EnableUserSwitching = false
+
+	daily := CrontabEntry{Spec: "@daily", User: "root", Command: "true", Source: "/etc/crontab"}
+	hourly := CrontabEntry{Spec: "@hourly", User: "root", Command: "true", Source: "/etc/crontab"}
+
+	for _, test := range []struct {
+		name    string
+		entries []CrontabEntry
+		jobs    int
+		errors  int
+	}{
+		{"valid", []CrontabEntry{daily, hourly}, 2, 0},
+		{"invalid nice", []CrontabEntry{{Spec: "@daily", User: "root", Command: "true", ProcAttr: ProcAttr{Nice: "99"}}}, 0, 1},
+		{"invalid timeout", []CrontabEntry{{Spec: "@daily", User: "root", Command: "true", Timeout: "soon"}}, 0, 1},
+	} {
+		set, err := NewRunner().CreateCronjobs(test.entries)
+
+		errors := 0
+		if loadErr, ok := err.(*LoadError); ok {
+			errors = len(loadErr.Errors)
+		} else if err != nil {
+			t.Errorf("%s: expected LoadError, got %v", test.name, err)
+		}
+
+		if len(set.jobs) != test.jobs || errors != test.errors {
+			t.Errorf("%s: expected %d jobs and %d errors, got %d jobs and %v", test.name, test.jobs, test.errors, len(set.jobs), err)
+		}
+	}
+
+	// job ids are stable across reloads
+	first, _ := NewRunner().CreateCronjobs([]CrontabEntry{daily, hourly})
+	second, _ := NewRunner().CreateCronjobs([]CrontabEntry{hourly, daily})
+	if first.jobs[0].Id != second.jobs[1].Id || first.jobs[1].Id != second.jobs[0].Id {
+		t.Errorf("expected stable job ids, got %d %d and %d %d", first.jobs[0].Id, first.jobs[1].Id, second.jobs[0].Id, second.jobs[1].Id)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	scheduleDescriptors = map[string]string{
+		"@yearly":   "every year (Jan 1st at 00:00)",
+		"@annually": "every year (Jan 1st at 00:00)",
+		"@monthly":  "every month (1st at 00:00)",
+		"@weekly":   "every week (Sunday at 00:00)",
+		"@daily":    "every day at 00:00",
+		"@midnight": "every day at 00:00",
+		"@hourly":   "every hour",
+	}
+
+	scheduleMonthNames   = []string{"", "Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	scheduleWeekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+)
+
+// Return human readable description of cron spec (empty if spec is too complex)
+func describeSchedule(spec string) string {
+	spec = strings.TrimSpace(spec)
+
+	if description, ok := scheduleDescriptors[strings.ToLower(spec)]; ok {
+		return description
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		return "every " + strings.TrimSpace(strings.TrimPrefix(spec, "@every "))
+	}
+
+	fields := strings.Fields(spec)
+	seconds := "0"
+	if len(fields) == 6 {
+		seconds, fields = fields[0], fields[1:]
+	}
+	if len(fields) != 5 || seconds != "0" {
+		return ""
+	}
+
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	var ret string
+	switch {
+	case minute == "*" && hour == "*":
+		ret = "every minute"
+	case scheduleStep(minute) > 0 && hour == "*":
+		ret = fmt.Sprintf("every %d minutes", scheduleStep(minute))
+	case scheduleIsNumber(minute) && hour == "*":
+		ret = fmt.Sprintf("every hour at minute %s", minute)
+	case scheduleIsNumber(minute) && scheduleStep(hour) > 0:
+		ret = fmt.Sprintf("every %d hours at minute %s", scheduleStep(hour), minute)
+	case scheduleIsNumber(minute) && scheduleIsList(hour):
+		var times []string
+		for _, h := range strings.Split(hour, ",") {
+			times = append(times, fmt.Sprintf("%02s:%02s", h, minute))
+		}
+		ret = "at " + strings.Join(times, ", ")
+	default:
+		return ""
+	}
+
+	if dom != "*" && dom != "?" {
+		ret += fmt.Sprintf(", on day %s of the month", dom)
+	}
+
+	if month != "*" {
+		names, ok := scheduleNames(month, scheduleMonthNames)
+		if !ok {
+			return ""
+		}
+		ret += ", in " + names
+	}
+
+	if dow != "*" && dow != "?" {
+		names, ok := scheduleNames(dow, scheduleWeekdayNames)
+		if !ok {
+			return ""
+		}
+		ret += ", on " + names
+	}
+
+	return ret
+}
+
+// Return step of "*/n" field (0 if field is not a step)
+func scheduleStep(field string) int {
+	if !strings.HasPrefix(field, "*/") {
+		return 0
+	}
+
+	step, err := strconv.Atoi(strings.TrimPrefix(field, "*/"))
+	if err != nil {
+		return 0
+	}
+	return step
+}
+
+func scheduleIsNumber(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+func scheduleIsList(field string) bool {
+	for _, value := range strings.Split(field, ",") {
+		if !scheduleIsNumber(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Replace numbers of list and range field by names (eg. 1-5 -> Mon-Fri)
+func scheduleNames(field string, names []string) (string, bool) {
+	var ret []string
+	for _, value := range strings.Split(field, ",") {
+		var parts []string
+		for _, part := range strings.Split(value, "-") {
+			i, err := strconv.Atoi(part)
+			if err != nil {
+				// already a name (eg. MON-FRI)
+				if len(part) != 3 {
+					return "", false
+				}
+				parts = append(parts, strings.ToUpper(part[:1])+strings.ToLower(part[1:]))
+				continue
+			}
+			if i < 0 || i >= len(names) || names[i] == "" {
+				return "", false
+			}
+			parts = append(parts, names[i])
+		}
+		ret = append(ret, strings.Join(parts, "-"))
+	}
+
+	return strings.Join(ret, ", "), true
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	STREAM_EVENT_STARTED  = "started"
+	STREAM_EVENT_OUTPUT   = "output"
+	STREAM_EVENT_FINISHED = "finished"
+
+	// number of events kept for late subscribers
+	STREAM_BACKLOG_SIZE = 1000
+
+	// slow subscribers are dropped if their buffer is full (jobs are never blocked)
+	STREAM_SUBSCRIBER_BUFFER = 256
+)
+
+// Lifecycle or output event of cronjob run
+type StreamEvent struct {
+	Type     string    `json:"type"` // started, output or finished
+	RunId    uint64    `json:"run_id"`
+	JobId    int       `json:"job_id"`
+	Time     time.Time `json:"time"`
+	Stream   string    `json:"stream,omitempty"` // stdout or stderr (output)
+	Line     string    `json:"line,omitempty"`   // output line without line break (output)
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	TimedOut bool      `json:"timed_out,omitempty"`
+	Duration float64   `json:"duration,omitempty"` // seconds (finished)
+}
+
+// Collects combined output of cronjob run and fans out lines to subscribers
+type OutputBroadcaster struct {
+	mu          sync.Mutex
+	jobId       int
+	runId       uint64
+	output      bytes.Buffer
+	backlog     []StreamEvent
+	subscribers map[chan StreamEvent]bool
+	closed      bool
+}
+
+// Writer of one output stream (stdout or stderr), splits output into lines
+type outputStreamWriter struct {
+	broadcaster *OutputBroadcaster
+	stream      string
+	partial     []byte
+}
+
+func NewOutputBroadcaster(jobId int, runId uint64, start time.Time) *OutputBroadcaster {
+	b := &OutputBroadcaster{
+		jobId:       jobId,
+		runId:       runId,
+		subscribers: map[chan StreamEvent]bool{},
+	}
+	b.publish(StreamEvent{Type: STREAM_EVENT_STARTED, Time: start})
+	return b
+}
+
+// Return writer for output stream (stdout or stderr)
+func (b *OutputBroadcaster) Writer(stream string) *outputStreamWriter {
+	return &outputStreamWriter{broadcaster: b, stream: stream}
+}
+
+func (w *outputStreamWriter) Write(p []byte) (int, error) {
+	w.broadcaster.mu.Lock()
+	w.broadcaster.output.Write(p)
+	w.broadcaster.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.broadcaster.publishLine(w.stream, string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Publish remaining output without line break
+func (w *outputStreamWriter) flush() {
+	if len(w.partial) > 0 {
+		w.broadcaster.publishLine(w.stream, string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (b *OutputBroadcaster) publishLine(stream string, line string) {
+	b.publish(StreamEvent{
+		Type:   STREAM_EVENT_OUTPUT,
+		Time:   time.Now(),
+		Stream: stream,
+		Line:   redactor.Redact(strings.TrimSuffix(line, "\r")),
+	})
+}
+
+func (b *OutputBroadcaster) publish(event StreamEvent) {
+	event.JobId = b.jobId
+	event.RunId = b.runId
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.backlog = append(b.backlog, event)
+	if len(b.backlog) > STREAM_BACKLOG_SIZE {
+		b.backlog = b.backlog[len(b.backlog)-STREAM_BACKLOG_SIZE:]
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// subscriber too slow
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Return combined output of run
+func (b *OutputBroadcaster) Output() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.output.Bytes()
+}
+
+// Publish finished event and close all subscribers
+func (b *OutputBroadcaster) Finish(err error, elapsed time.Duration, timedOut bool) {
+	code := exitCode(err)
+	event := StreamEvent{
+		Type:     STREAM_EVENT_FINISHED,
+		Time:     time.Now(),
+		ExitCode: &code,
+		TimedOut: timedOut,
+		Duration: elapsed.Seconds(),
+	}
+	if err != nil {
+		event.Error = redactor.Redact(err.Error())
+	}
+	b.publish(event)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscriber := range b.subscribers {
+		close(subscriber)
+	}
+	b.subscribers = map[chan StreamEvent]bool{}
+}
+
+// Subscribe to events, returns past events and channel for new events (closed after run finished)
+func (b *OutputBroadcaster) Subscribe() ([]StreamEvent, chan StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backlog := make([]StreamEvent, len(b.backlog))
+	copy(backlog, b.backlog)
+
+	subscriber := make(chan StreamEvent, STREAM_SUBSCRIBER_BUFFER)
+	if b.closed {
+		close(subscriber)
+	} else {
+		b.subscribers[subscriber] = true
+	}
+
+	return backlog, subscriber
+}
+
+// Unsubscribe (eg. client disconnected)
+func (b *OutputBroadcaster) Unsubscribe(subscriber chan StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[subscriber] {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// Return events of finished run from run history (for clients connecting after the run)
+func runStreamEvents(run Run) []StreamEvent {
+	events := []StreamEvent{{Type: STREAM_EVENT_STARTED, RunId: run.Id, JobId: run.JobId, Time: run.Start}}
+
+	if run.Output != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(run.Output, "\n"), "\n") {
+			events = append(events, StreamEvent{Type: STREAM_EVENT_OUTPUT, RunId: run.Id, JobId: run.JobId, Time: run.End, Line: line})
+		}
+	}
+
+	exitCode := run.ExitCode
+	events = append(events, StreamEvent{
+		Type:     STREAM_EVENT_FINISHED,
+		RunId:    run.Id,
+		JobId:    run.JobId,
+		Time:     run.End,
+		ExitCode: &exitCode,
+		Error:    run.Error,
+		TimedOut: run.TimedOut,
+		Duration: run.Elapsed.Seconds(),
+	})
+
+	return events
+}
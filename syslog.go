@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SYSLOG_FORMAT_RFC3164 = "rfc3164"
+	SYSLOG_FORMAT_RFC5424 = "rfc5424"
+
+	SYSLOG_TAG       = "go-crond"
+	SYSLOG_TAG_LIMIT = 32
+
+	SYSLOG_QUEUE_SIZE      = 1024
+	SYSLOG_DIAL_TIMEOUT    = 5 * time.Second
+	SYSLOG_WRITE_TIMEOUT   = 1 * time.Second
+	SYSLOG_BACKOFF_INITIAL = 1 * time.Second
+	SYSLOG_BACKOFF_MAX     = 1 * time.Minute
+)
+
+var (
+	syslogFacilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+		"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+		"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+	}
+
+	syslogTagCleanupRegexp = regexp.MustCompile(`[^\w\.\-]+`)
+)
+
+// Log sink for syslog (RFC 3164 or RFC 5424) over unix socket, udp or tcp
+//
+// Messages are sent in background, logging never waits for the syslog server. If the
+// queue is full or the server is unreachable (reconnect with backoff) messages are dropped.
+type SyslogSink struct {
+	network  string
+	address  string
+	format   string
+	facility int
+	hostname string
+
+	queue   chan string
+	dropped uint64
+
+	// used by sender goroutine only
+	conn    net.Conn
+	retryAt time.Time
+	backoff time.Duration
+}
+
+// Create syslog sink (address format: unix:/dev/log, udp:host:port, tcp:host:port)
+func NewSyslogSink(address string, format string, facility string) (*SyslogSink, error) {
+	split := strings.SplitN(address, ":", 2)
+	if len(split) != 2 {
+		return nil, fmt.Errorf("invalid syslog address %q (expected unix:path, udp:host:port or tcp:host:port)", address)
+	}
+
+	sink := &SyslogSink{network: split[0], address: split[1], format: format, queue: make(chan string, SYSLOG_QUEUE_SIZE), backoff: SYSLOG_BACKOFF_INITIAL}
+
+	switch sink.network {
+	case "unix", "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("invalid syslog network %q (expected unix, udp or tcp)", sink.network)
+	}
+
+	switch sink.format {
+	case SYSLOG_FORMAT_RFC3164, SYSLOG_FORMAT_RFC5424:
+	default:
+		return nil, fmt.Errorf("invalid syslog format %q", sink.format)
+	}
+
+	var ok bool
+	if sink.facility, ok = syslogFacilities[facility]; !ok {
+		return nil, fmt.Errorf("invalid syslog facility %q", facility)
+	}
+
+	sink.hostname, _ = os.Hostname()
+	if sink.hostname == "" {
+		sink.hostname = "-"
+	}
+
+	go sink.run()
+
+	return sink, nil
+}
+
+func (sink *SyslogSink) connect() error {
+	if sink.conn != nil {
+		return nil
+	}
+
+	var err error
+	if sink.network == "unix" {
+		// /dev/log is usually a datagram socket
+		sink.conn, err = net.DialTimeout("unixgram", sink.address, SYSLOG_DIAL_TIMEOUT)
+		if err != nil {
+			sink.conn, err = net.DialTimeout("unix", sink.address, SYSLOG_DIAL_TIMEOUT)
+		}
+	} else {
+		sink.conn, err = net.DialTimeout(sink.network, sink.address, SYSLOG_DIAL_TIMEOUT)
+	}
+
+	return err
+}
+
+// Queue record (called with log lock held, must not block)
+func (sink *SyslogSink) Write(record LogRecord) {
+	select {
+	case sink.queue <- sink.Format(record):
+	default:
+		atomic.AddUint64(&sink.dropped, 1)
+	}
+}
+
+// Send queued messages
+func (sink *SyslogSink) run() {
+	for msg := range sink.queue {
+		if !sink.send(msg) {
+			atomic.AddUint64(&sink.dropped, 1)
+		} else if dropped := atomic.SwapUint64(&sink.dropped, 0); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "%sdropped %d syslog messages\n", LogPrefix, dropped)
+		}
+	}
+}
+
+// Send message, reconnects once if connection was lost and waits with backoff
+// before connecting again after failures
+func (sink *SyslogSink) send(msg string) bool {
+	for retry := 0; retry < 2; retry++ {
+		if sink.conn == nil && time.Now().Before(sink.retryAt) {
+			return false
+		}
+
+		if err := sink.connect(); err != nil {
+			fmt.Fprintf(os.Stderr, "%scannot connect to syslog %s:%s (retry in %s): %v\n", LogPrefix, sink.network, sink.address, sink.backoff, err)
+			sink.retryAt = time.Now().Add(sink.backoff)
+			sink.backoff *= 2
+			if sink.backoff > SYSLOG_BACKOFF_MAX {
+				sink.backoff = SYSLOG_BACKOFF_MAX
+			}
+			return false
+		}
+		sink.backoff = SYSLOG_BACKOFF_INITIAL
+
+		frame := msg
+		switch sink.conn.LocalAddr().Network() {
+		case "tcp":
+			// octet counting framing (RFC 6587)
+			frame = fmt.Sprintf("%d %s", len(msg), msg)
+		case "unix":
+			// stream socket, messages are separated by newlines
+			frame = msg + "\n"
+		}
+
+		sink.conn.SetWriteDeadline(time.Now().Add(SYSLOG_WRITE_TIMEOUT))
+		if _, err := sink.conn.Write([]byte(frame)); err == nil {
+			return true
+		}
+
+		sink.conn.Close()
+		sink.conn = nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%scannot write to syslog %s:%s\n", LogPrefix, sink.network, sink.address)
+	return false
+}
+
+// Format record as syslog message
+func (sink *SyslogSink) Format(record LogRecord) string {
+	priority := sink.facility*8 + syslogSeverity(record.Level)
+	tag := syslogTag(record)
+	message := formatTextRecord(record)
+
+	if sink.format == SYSLOG_FORMAT_RFC5424 {
+		return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+			priority, record.Time.Format(time.RFC3339Nano), sink.hostname, tag, os.Getpid(), syslogMsgId(record), message)
+	}
+
+	return fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		priority, record.Time.Format(time.Stamp), sink.hostname, tag, os.Getpid(), message)
+}
+
+// Syslog severity of log level
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case LevelDebug:
+		return 7
+	case LevelWarn:
+		return 4
+	case LevelError:
+		return 3
+	}
+	return 6
+}
+
+// Syslog tag, cronjob events are tagged with the cronjob name
+func syslogTag(record LogRecord) string {
+	tag := SYSLOG_TAG
+
+	if name, ok := record.Field("job_name"); ok {
+		if cleaned := syslogTagCleanupRegexp.ReplaceAllString(fmt.Sprint(name), "_"); cleaned != "" {
+			tag = cleaned
+		}
+	}
+
+	if len(tag) > SYSLOG_TAG_LIMIT {
+		tag = tag[:SYSLOG_TAG_LIMIT]
+	}
+
+	return tag
+}
+
+// RFC 5424 message id (event name without spaces)
+func syslogMsgId(record LogRecord) string {
+	if _, ok := record.Field("job_name"); !ok {
+		return "-"
+	}
+	return strings.Replace(record.Message, " ", "_", -1)
+}
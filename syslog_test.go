@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSyslogRecord(level LogLevel, message string, fields ...LogField) LogRecord {
+	return LogRecord{
+		Time:    time.Date(2017, 6, 1, 2, 3, 4, 0, time.UTC),
+		Level:   level,
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+func TestSyslogFormatRfc3164(t *testing.T) {
+	sink, err := NewSyslogSink("udp:127.0.0.1:514", SYSLOG_FORMAT_RFC3164, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := sink.Format(testSyslogRecord(LevelError, "failed", LogField{Key: "job_name", Value: "my backup"}))
+
+	// facility cron (9), severity error (3)
+	expected := regexp.MustCompile(fmt.Sprintf(`^<75>Jun  1 02:03:04 \S+ my_backup\[%d\]: failed job_name=my backup$`, os.Getpid()))
+	if !expected.MatchString(msg) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestSyslogFormatRfc5424(t *testing.T) {
+	sink, err := NewSyslogSink("udp:127.0.0.1:514", SYSLOG_FORMAT_RFC5424, "local0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// facility local0 (16), severity info (6), no cronjob: default tag, no message id and no structured data
+	msg := sink.Format(testSyslogRecord(LevelInfo, "started"))
+	expected := regexp.MustCompile(fmt.Sprintf(`^<134>1 2017-06-01T02:03:04Z \S+ go-crond %d - - started$`, os.Getpid()))
+	if !expected.MatchString(msg) {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	// severity warn (4), message id of cronjob event
+	msg = sink.Format(testSyslogRecord(LevelWarn, "cronjob finished", LogField{Key: "job_name", Value: "backup"}))
+	expected = regexp.MustCompile(fmt.Sprintf(`^<132>1 2017-06-01T02:03:04Z \S+ backup %d cronjob_finished - cronjob finished job_name=backup$`, os.Getpid()))
+	if !expected.MatchString(msg) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestSyslogTagLimit(t *testing.T) {
+	tag := syslogTag(testSyslogRecord(LevelInfo, "exec", LogField{Key: "job_name", Value: strings.Repeat("x", 50)}))
+	if len(tag) != SYSLOG_TAG_LIMIT {
+		t.Errorf("expected tag with %d characters, got %q", SYSLOG_TAG_LIMIT, tag)
+	}
+}
+
+func TestSyslogSinkInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"localhost:514", SYSLOG_FORMAT_RFC3164, "cron"},
+		{"udp", SYSLOG_FORMAT_RFC3164, "cron"},
+		{"udp:localhost:514", "rfc1234", "cron"},
+		{"udp:localhost:514", SYSLOG_FORMAT_RFC3164, "unknown"},
+	} {
+		if _, err := NewSyslogSink(args[0], args[1], args[2]); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestSyslogWriteUdp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp:"+conn.LocalAddr().String(), SYSLOG_FORMAT_RFC3164, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := testSyslogRecord(LevelInfo, "hello")
+	sink.Write(record)
+
+	// one datagram per message without framing
+	expectSyslogPacket(t, conn, sink.Format(record))
+}
+
+func TestSyslogWriteUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("unix:"+path, SYSLOG_FORMAT_RFC5424, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := testSyslogRecord(LevelError, "failed", LogField{Key: "job_name", Value: "backup"})
+	sink.Write(record)
+
+	expectSyslogPacket(t, conn, sink.Format(record))
+}
+
+func TestSyslogWriteTcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewSyslogSink("tcp:"+listener.Addr().String(), SYSLOG_FORMAT_RFC5424, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []LogRecord{testSyslogRecord(LevelInfo, "first"), testSyslogRecord(LevelInfo, "second")}
+	go func() {
+		for _, record := range records {
+			sink.Write(record)
+		}
+	}()
+
+	// octet counting framing (RFC 6587)
+	var expected string
+	for _, record := range records {
+		msg := sink.Format(record)
+		expected += fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	expectSyslogStream(t, listener, expected)
+}
+
+func TestSyslogWriteUnixStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewSyslogSink("unix:"+path, SYSLOG_FORMAT_RFC3164, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []LogRecord{testSyslogRecord(LevelInfo, "first"), testSyslogRecord(LevelWarn, "second")}
+	go func() {
+		for _, record := range records {
+			sink.Write(record)
+		}
+	}()
+
+	// stream socket, messages are separated by newlines
+	var expected string
+	for _, record := range records {
+		expected += sink.Format(record) + "\n"
+	}
+	expectSyslogStream(t, listener, expected)
+}
+
+func expectSyslogPacket(t *testing.T, conn net.PacketConn, expected string) {
+	t.Helper()
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != expected {
+		t.Errorf("expected %q, got %q", expected, string(buf[:n]))
+	}
+}
+
+func expectSyslogStream(t *testing.T, listener net.Listener, expected string) {
+	t.Helper()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	received := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, received); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(received) != expected {
+		t.Errorf("expected %q, got %q", expected, string(received))
+	}
+}
+
+func TestSyslogWriteUnreachable(t *testing.T) {
+	// address without listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	sink, err := NewSyslogSink("tcp:"+address, SYSLOG_FORMAT_RFC5424, "cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// logging must not wait for the syslog server, messages exceeding the queue are dropped
+	start := time.Now()
+	for i := 0; i < SYSLOG_QUEUE_SIZE*2; i++ {
+		sink.Write(testSyslogRecord(LevelInfo, "message"))
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writing to unreachable syslog server took %s", elapsed)
+	}
+}
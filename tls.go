@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+var (
+	httpTls = &TlsReloader{}
+)
+
+// Certificate and client ca for the http listener, reloaded on SIGHUP
+type TlsReloader struct {
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// Load certificate, key and client ca (mTLS), previous files are kept on errors
+func (t *TlsReloader) Load(certFile string, keyFile string, clientCAFile string) error {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load tls certificate: %v", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if clientCAFile != "" {
+		content, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return fmt.Errorf("cannot load tls client ca: %v", err)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return fmt.Errorf("cannot load tls client ca: no certificates found in %s", clientCAFile)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.certificate = &certificate
+	t.clientCAs = clientCAs
+
+	return nil
+}
+
+// Return tls config, certificates are looked up for every connection
+func (t *TlsReloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			t.mu.RLock()
+			defer t.mu.RUnlock()
+
+			return t.certificate, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			t.mu.RLock()
+			defer t.mu.RUnlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*t.certificate},
+			}
+
+			if t.clientCAs != nil {
+				config.ClientCAs = t.clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return config, nil
+		},
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	UI_TIME_FORMAT = "2006-01-02 15:04:05"
+)
+
+var (
+	//go:embed ui/templates ui/static
+	uiFiles embed.FS
+
+	uiTemplateFuncs = template.FuncMap{
+		"time": func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.Format(UI_TIME_FORMAT)
+		},
+		"duration": func(d time.Duration) string {
+			return d.Round(time.Millisecond).String()
+		},
+		"since": func(t time.Time) string {
+			return time.Since(t).Round(time.Second).String()
+		},
+	}
+)
+
+// Cronjob with schedule and last run for web interface
+type uiJob struct {
+	Job
+	Title    string
+	Schedule string
+	State    string
+	LastRun  *Run
+}
+
+type uiFilter struct {
+	Tag    string
+	User   string
+	Source string
+}
+
+type uiIndexParams struct {
+	Jobs    []uiJob
+	Tags    []string
+	Users   []string
+	Sources []string
+	Filter  uiFilter
+	JobLogs bool
+}
+
+type uiJobParams struct {
+	Job     uiJob
+	Runs    []Run
+	JobLogs bool
+}
+
+type uiRunParams struct {
+	Job *uiJob
+	Run Run
+}
+
+// Handler of web interface (job table, job details and run output)
+type UiHandler struct {
+	runner    *Runner
+	templates *template.Template
+}
+
+func NewUiHandler(runner *Runner) (*UiHandler, error) {
+	templates, err := template.New("ui").Funcs(uiTemplateFuncs).ParseFS(uiFiles, "ui/templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	return &UiHandler{runner: runner, templates: templates}, nil
+}
+
+// Handler for embedded static assets (/static/)
+func uiStaticHandler() http.Handler {
+	static, _ := fs.Sub(uiFiles, "ui/static")
+	return http.StripPrefix("/static/", http.FileServer(http.FS(static)))
+}
+
+func (h *UiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.URL.Path == "/":
+		h.index(w, r)
+	case len(path) == 2 && path[0] == "jobs":
+		h.job(w, path[1])
+	case len(path) == 2 && path[0] == "runs":
+		h.run(w, path[1])
+	case len(path) == 3 && path[0] == "runs" && path[2] == "stream":
+		h.stream(w, r, path[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *UiHandler) index(w http.ResponseWriter, r *http.Request) {
+	params := uiIndexParams{
+		Filter: uiFilter{
+			Tag:    r.URL.Query().Get("tag"),
+			User:   r.URL.Query().Get("user"),
+			Source: r.URL.Query().Get("source"),
+		},
+		JobLogs: opts.JobLogDir != "",
+	}
+
+	tags, users, sources := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, job := range h.runner.GetJobs() {
+		for _, tag := range job.Tags {
+			tags[tag] = true
+		}
+		users[job.User] = true
+		sources[job.Source] = true
+
+		if params.Filter.Tag != "" && !stringInSlice(params.Filter.Tag, job.Tags) {
+			continue
+		}
+		if params.Filter.User != "" && params.Filter.User != job.User {
+			continue
+		}
+		if params.Filter.Source != "" && params.Filter.Source != job.Source {
+			continue
+		}
+
+		params.Jobs = append(params.Jobs, h.uiJob(job))
+	}
+
+	params.Tags = uiSortedKeys(tags)
+	params.Users = uiSortedKeys(users)
+	params.Sources = uiSortedKeys(sources)
+
+	h.render(w, "index.html", params)
+}
+
+func (h *UiHandler) job(w http.ResponseWriter, id string) {
+	jobId, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	job, ok := h.runner.GetJob(jobId)
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	h.render(w, "job.html", uiJobParams{
+		Job:     h.uiJob(job),
+		Runs:    h.runner.History().List(job.Id),
+		JobLogs: opts.JobLogDir != "",
+	})
+}
+
+func (h *UiHandler) run(w http.ResponseWriter, id string) {
+	runId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	run, ok := h.runner.History().Get(runId)
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+
+	params := uiRunParams{Run: run}
+	if job, ok := h.runner.GetJob(run.JobId); ok {
+		uiJob := h.uiJob(job)
+		params.Job = &uiJob
+	}
+
+	h.render(w, "run.html", params)
+}
+
+// Live tail of run (same server-sent events as /api/v1/runs/<id>/stream, also without api listener)
+func (h *UiHandler) stream(w http.ResponseWriter, r *http.Request, id string) {
+	NewApiHandler(h.runner).runStream(w, r, id)
+}
+
+func (h *UiHandler) uiJob(job Job) uiJob {
+	ret := uiJob{
+		Job:      job,
+		Title:    job.Name,
+		Schedule: describeSchedule(job.Spec),
+		State:    jobState(job),
+	}
+
+	if ret.Title == "" {
+		ret.Title = job.Command
+	}
+
+	if runs := h.runner.History().List(job.Id); len(runs) > 0 {
+		ret.LastRun = &runs[0]
+	}
+
+	return ret
+}
+
+func (h *UiHandler) render(w http.ResponseWriter, name string, params interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templates.ExecuteTemplate(w, name, params); err != nil {
+		LoggerError.Printf("Cannot render web interface: %v", err)
+	}
+}
+
+func uiSortedKeys(values map[string]bool) []string {
+	var ret []string
+	for value := range values {
+		if value != "" {
+			ret = append(ret, value)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,30 @@
+// live tail of running cronjob (server-sent events of /runs/<id>/stream)
+(function () {
+    var output = document.querySelector("pre.output[data-stream]");
+    if (!output || !window.EventSource) {
+        return;
+    }
+
+    output.textContent = "";
+
+    var source = new EventSource(output.getAttribute("data-stream"));
+
+    source.addEventListener("output", function (e) {
+        var event = JSON.parse(e.data);
+        var follow = output.scrollTop + output.clientHeight >= output.scrollHeight - 5;
+
+        var line = document.createElement("span");
+        line.className = "line-" + event.stream;
+        line.textContent = event.line + "\n";
+        output.appendChild(line);
+
+        if (follow) {
+            output.scrollTop = output.scrollHeight;
+        }
+    });
+
+    source.addEventListener("finished", function () {
+        source.close();
+        window.location.reload();
+    });
+})();
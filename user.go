@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// default PATH for cronjobs running as other user (like cron)
+	DEFAULT_PATH = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+	// default login shell if passwd entry has none
+	DEFAULT_LOGIN_SHELL = "/bin/sh"
+
+	// timeout for getent lookups
+	GETENT_TIMEOUT = 10 * time.Second
+)
+
+// Lookup login shell of user with getent (NSS, eg. LDAP or sssd users), falls back to /etc/passwd
+func lookupUserShell(username string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), GETENT_TIMEOUT)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, "getent", "passwd", username).Output(); err == nil {
+		if shell, ok := passwdShell(strings.TrimSpace(string(output)), username); ok {
+			return shell
+		}
+	}
+
+	file, err := os.Open("/etc/passwd")
+	if err != nil {
+		return DEFAULT_LOGIN_SHELL
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if shell, ok := passwdShell(scanner.Text(), username); ok {
+			return shell
+		}
+	}
+
+	return DEFAULT_LOGIN_SHELL
+}
+
+// Return shell of passwd line if it belongs to user
+func passwdShell(line string, username string) (string, bool) {
+	// format: name:password:uid:gid:gecos:home:shell
+	fields := strings.Split(line, ":")
+	if len(fields) == 7 && fields[0] == username && fields[6] != "" {
+		return fields[6], true
+	}
+	return "", false
+}
+
+// Lookup supplementary group ids of user (like initgroups)
+func lookupUserGroups(u *user.User) ([]uint32, error) {
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []uint32
+	for _, groupId := range groupIds {
+		gid, err := strconv.ParseUint(groupId, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, uint32(gid))
+	}
+
+	return ret, nil
+}
+
+var (
+	identityCache = NewIdentityCache()
+)
+
+// Resolved user and group of a cronjob
+type Identity struct {
+	Username string
+	Uid      uint32
+	Gid      uint32
+	Groups   []uint32
+	HomeDir  string
+	Shell    string
+}
+
+// Resolve user specification (format: user, uid, user:group, uid:gid, :group)
+//
+// Numeric ids don't need an entry in /etc/passwd or /etc/group, the group
+// defaults to the primary group of the user (or gid = uid if the user is unknown).
+func resolveIdentity(spec string) (*Identity, error) {
+	userSpec, groupSpec := spec, ""
+	if strings.Contains(spec, ":") {
+		split := strings.SplitN(spec, ":", 2)
+		userSpec, groupSpec = split[0], split[1]
+	}
+
+	if userSpec == "" {
+		userSpec = opts.DefaultUser
+	}
+
+	ret := &Identity{Username: userSpec, HomeDir: "/", Shell: DEFAULT_LOGIN_SHELL}
+
+	var u *user.User
+	if uid, err := strconv.ParseUint(userSpec, 10, 32); err == nil {
+		ret.Uid = uint32(uid)
+
+		// numeric uid, passwd entry is optional
+		if lookup, err := user.LookupId(userSpec); err == nil {
+			u = lookup
+		}
+	} else {
+		lookup, err := user.Lookup(userSpec)
+		if err != nil {
+			return nil, fmt.Errorf("user lookup failed: %v", err)
+		}
+		u = lookup
+
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert user to id: %v", err)
+		}
+		ret.Uid = uint32(uid)
+	}
+
+	if u != nil {
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert group to id: %v", err)
+		}
+		ret.Gid = uint32(gid)
+
+		groups, err := lookupUserGroups(u)
+		if err != nil {
+			return nil, fmt.Errorf("cannot lookup groups of user %v: %v", u.Username, err)
+		}
+		ret.Groups = groups
+
+		ret.Username = u.Username
+		ret.HomeDir = u.HomeDir
+		ret.Shell = lookupUserShell(u.Username)
+	} else {
+		// unknown numeric uid, never fall back to the root group
+		ret.Gid = ret.Uid
+	}
+
+	if groupSpec != "" {
+		if gid, err := strconv.ParseUint(groupSpec, 10, 32); err == nil {
+			ret.Gid = uint32(gid)
+		} else {
+			g, err := user.LookupGroup(groupSpec)
+			if err != nil {
+				return nil, fmt.Errorf("group lookup failed: %v", err)
+			}
+
+			gid, err := strconv.ParseUint(g.Gid, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert group to id: %v", err)
+			}
+			ret.Gid = uint32(gid)
+		}
+	}
+
+	return ret, nil
+}
+
+// Login environment of user (HOME, USER, LOGNAME, SHELL and PATH)
+func (identity *Identity) Environment() []string {
+	return []string{
+		"HOME=" + identity.HomeDir,
+		"USER=" + identity.Username,
+		"LOGNAME=" + identity.Username,
+		"SHELL=" + identity.Shell,
+		"PATH=" + DEFAULT_PATH,
+	}
+}
+
+// Cache for resolved identities (avoids NSS lookups on every cronjob execution)
+type IdentityCache struct {
+	mu      sync.Mutex
+	entries map[string]*identityCacheEntry
+	errors  map[string]int
+}
+
+type identityCacheEntry struct {
+	identity *Identity
+	resolved time.Time
+}
+
+func NewIdentityCache() *IdentityCache {
+	return &IdentityCache{
+		entries: map[string]*identityCacheEntry{},
+		errors:  map[string]int{},
+	}
+}
+
+// Return identity for user specification, resolves it again if the cache ttl is expired
+//
+// If resolving fails the last known identity is used. Lookups (NSS, possibly slow
+// directory services) are done without holding the lock.
+func (c *IdentityCache) Get(spec string) (*Identity, error) {
+	c.mu.Lock()
+	var cached identityCacheEntry
+	entry, exists := c.entries[spec]
+	if exists {
+		cached = *entry
+	}
+	c.mu.Unlock()
+
+	if exists && time.Since(cached.resolved) < opts.UserCacheTTL {
+		return cached.identity, nil
+	}
+
+	identity, err := resolveIdentity(spec)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.errors[spec]++
+
+		if exists {
+			LoggerError.Printf("Cannot resolve user %v, using last known uid:%d gid:%d: %v", spec, cached.identity.Uid, cached.identity.Gid, err)
+			return cached.identity, nil
+		}
+
+		return nil, err
+	}
+
+	c.entries[spec] = &identityCacheEntry{identity: identity, resolved: time.Now()}
+	return identity, nil
+}
+
+// Expire all cached identities, they are resolved again on next usage
+func (c *IdentityCache) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		entry.resolved = time.Time{}
+	}
+}
+
+// Return number of failed lookups per user specification
+func (c *IdentityCache) Errors() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := map[string]int{}
+	for spec, count := range c.errors {
+		ret[spec] = count
+	}
+	return ret
+}
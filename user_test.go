@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPasswdShell(t *testing.T) {
+	for _, test := range []struct {
+		line     string
+		username string
+		shell    string
+		ok       bool
+	}{
+		{"backup:x:34:34:backup:/var/backups:/usr/sbin/nologin", "backup", "/usr/sbin/nologin", true},
+		{"ldapuser:*:10001:10001:LDAP User:/home/ldapuser:/bin/zsh", "ldapuser", "/bin/zsh", true},
+		{"backup:x:34:34:backup:/var/backups:/usr/sbin/nologin", "back", "", false},
+		{"backup:x:34:34:backup:/var/backups:", "backup", "", false},
+		{"backup:x:34:34", "backup", "", false},
+		{"", "backup", "", false},
+	} {
+		shell, ok := passwdShell(test.line, test.username)
+		if shell != test.shell || ok != test.ok {
+			t.Errorf("passwdShell(%q, %q): expected %q %v, got %q %v", test.line, test.username, test.shell, test.ok, shell, ok)
+		}
+	}
+}
+
+func TestIdentityCache(t *testing.T) {
+	initLogger()
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+	opts.UserCacheTTL = time.Hour
+
+	cache := NewIdentityCache()
+
+	first, err := cache.Get("54321")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cached within ttl
+	second, err := cache.Get("54321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected cached identity within ttl")
+	}
+
+	// resolved again after expire
+	cache.Expire()
+	third, err := cache.Get("54321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first || third.Uid != 54321 {
+		t.Errorf("expected newly resolved identity after expire, got %+v", third)
+	}
+
+	// unknown user without cache entry
+	if _, err := cache.Get("go-crond-unknown-user"); err == nil {
+		t.Error("expected error for unknown user")
+	}
+
+	// last known identity is used if lookup fails
+	known := &Identity{Username: "go-crond-removed-user", Uid: 2000, Gid: 2000}
+	cache.entries["go-crond-removed-user"] = &identityCacheEntry{identity: known}
+	identity, err := cache.Get("go-crond-removed-user")
+	if err != nil || identity != known {
+		t.Errorf("expected last known identity, got %+v (%v)", identity, err)
+	}
+
+	errors := cache.Errors()
+	if errors["go-crond-unknown-user"] != 1 || errors["go-crond-removed-user"] != 1 {
+		t.Errorf("unexpected lookup errors %v", errors)
+	}
+}
+
+func TestResolveIdentity(t *testing.T) {
+	previous := opts
+	t.Cleanup(func() { opts = previous })
+	opts.DefaultUser = "root"
+
+	for _, test := range []struct {
+		spec     string
+		username string
+		uid      uint32
+		gid      uint32
+		valid    bool
+	}{
+		{"root", "root", 0, 0, true},
+		{"0", "root", 0, 0, true},
+		{"root:54321", "root", 0, 54321, true},
+		{"root:root", "root", 0, 0, true},
+		{":54321", "root", 0, 54321, true},
+		{"54321", "54321", 54321, 54321, true},
+		{"54321:54322", "54321", 54321, 54322, true},
+		{"54321:root", "54321", 54321, 0, true},
+		{"go-crond-unknown-user", "", 0, 0, false},
+		{"root:go-crond-unknown-group", "", 0, 0, false},
+		{"-1", "", 0, 0, false},
+	} {
+		identity, err := resolveIdentity(test.spec)
+		if (err == nil) != test.valid {
+			t.Errorf("resolveIdentity(%q): expected valid %v, got error %v", test.spec, test.valid, err)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+		if identity.Username != test.username || identity.Uid != test.uid || identity.Gid != test.gid {
+			t.Errorf("resolveIdentity(%q): expected %s %d:%d, got %s %d:%d", test.spec, test.username, test.uid, test.gid, identity.Username, identity.Uid, identity.Gid)
+		}
+	}
+}
+
+func TestResolveIdentityUnknownUid(t *testing.T) {
+	identity, err := resolveIdentity("54321")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"HOME=/", "USER=54321", "LOGNAME=54321", "SHELL=" + DEFAULT_LOGIN_SHELL, "PATH=" + DEFAULT_PATH}
+	if environ := identity.Environment(); fmt.Sprint(environ) != fmt.Sprint(expected) {
+		t.Errorf("expected environment %v, got %v", expected, environ)
+	}
+	if len(identity.Groups) != 0 {
+		t.Errorf("expected no supplementary groups, got %v", identity.Groups)
+	}
+}
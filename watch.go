@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch crontab files, include and run-parts directories and reload on changes (--watch)
+//
+// Parent directories are watched as well, so replaced files (editors, atomic updates
+// of kubernetes configmaps via ..data symlink swaps) and created directories are noticed.
+type CrontabWatcher struct {
+	watcher  *fsnotify.Watcher
+	paths    []string
+	debounce time.Duration
+	watched  map[string]bool
+}
+
+func NewCrontabWatcher(paths []string, debounce time.Duration) (*CrontabWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &CrontabWatcher{
+		watcher:  watcher,
+		paths:    paths,
+		debounce: debounce,
+		watched:  map[string]bool{},
+	}
+	w.sync()
+
+	return w, nil
+}
+
+// Update watched directories (parent directories of paths, directories and their subdirectories)
+//
+// For missing paths the nearest existing parent directory is watched until they are created.
+func (w *CrontabWatcher) sync() {
+	dirs := map[string]bool{}
+	for _, path := range w.paths {
+		parent := filepath.Dir(path)
+		for !checkIfDirectoryExists(parent) && parent != filepath.Dir(parent) {
+			parent = filepath.Dir(parent)
+		}
+		dirs[parent] = true
+
+		if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+			filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+				if err == nil && f.IsDir() {
+					dirs[path] = true
+				}
+				return nil
+			})
+		}
+	}
+
+	for dir := range dirs {
+		if w.watched[dir] {
+			continue
+		}
+
+		if err := w.watcher.Add(dir); err != nil {
+			LoggerError.Printf("Cannot watch %s: %v", dir, err)
+			continue
+		}
+		w.watched[dir] = true
+	}
+
+	for dir := range w.watched {
+		if !dirs[dir] {
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+}
+
+// Check if event affects a crontab file or directory
+func (w *CrontabWatcher) relevant(event fsnotify.Event) bool {
+	for _, path := range w.paths {
+		if event.Name == path || strings.HasPrefix(event.Name, path+string(filepath.Separator)) {
+			return true
+		}
+
+		// created or removed parent directory
+		if strings.HasPrefix(path, event.Name+string(filepath.Separator)) {
+			return true
+		}
+
+		// atomic update of kubernetes configmap next to crontab file
+		if filepath.Dir(event.Name) == filepath.Dir(path) && strings.HasPrefix(filepath.Base(event.Name), "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// Wait for changes and request reload after debounce time without further changes
+func (w *CrontabWatcher) Run() {
+	var timer <-chan time.Time
+	changed := ""
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if w.relevant(event) {
+				if changed == "" {
+					changed = event.Name
+				}
+				timer = time.After(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			// events might be lost (eg. queue overflow), reload to be safe
+			LoggerError.Printf("Crontab watch error: %v", err)
+			if changed == "" {
+				changed = "unknown path"
+			}
+			timer = time.After(w.debounce)
+		case <-timer:
+			LoggerInfo.Printf("Crontab changed: %s", changed)
+			timer = nil
+			changed = ""
+
+			reloadAndWait(context.Background(), "file change")
+
+			// watch new directories
+			w.sync()
+		}
+	}
+}